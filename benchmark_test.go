@@ -1,8 +1,12 @@
 package slog_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"io/ioutil"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tomorrowsky/slog"
 	"github.com/tomorrowsky/slog/handler"
@@ -54,3 +58,101 @@ func TestLogger_Info_Positive(t *testing.T) {
 
 	logger.Info("rate", "15", "low", 16, "high", 123.2, msg)
 }
+
+func BenchmarkTextFormatter(b *testing.B) {
+	f := slog.NewTextFormatter("[{{datetime}}] [{{channel}}] [{{level|pad:7}}] {{message}}{{?data}} {{data}}{{/data}} {{user}}\n")
+	r := &slog.Record{
+		Time:    time.Now(),
+		Level:   slog.InfoLevel,
+		Channel: "app",
+		Message: msg,
+		Data:    slog.M{"rate": 15},
+		Fields:  slog.M{"user": "inhere"},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = f.Format(r)
+	}
+}
+
+// the replacer based rendering before the template is compiled, for compare
+func BenchmarkTextFormatter_Replacer(b *testing.B) {
+	tpl := "[{{datetime}}] [{{channel}}] [{{level}}] {{message}} {{data}} {{user}}\n"
+	r := &slog.Record{
+		Time:    time.Now(),
+		Level:   slog.InfoLevel,
+		Channel: "app",
+		Message: msg,
+		Data:    slog.M{"rate": 15},
+		Fields:  slog.M{"user": "inhere"},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		str := strings.NewReplacer(
+			"{{datetime}}", r.Time.Format(slog.DefaultTimeFormat),
+			"{{channel}}", r.Channel,
+			"{{level}}", r.LevelName(),
+			"{{message}}", r.Message,
+			"{{data}}", slog.EncodeToString(r.Data),
+			"{{user}}", slog.EncodeToString(r.Fields["user"]),
+		).Replace(tpl)
+		_ = []byte(str)
+	}
+}
+
+func BenchmarkJSONFormatter(b *testing.B) {
+	f := slog.NewJSONFormatter()
+	r := &slog.Record{
+		Time:    time.Now(),
+		Level:   slog.InfoLevel,
+		Channel: "app",
+		Message: msg,
+		Data:    slog.M{"rate": 15, "low": 16, "high": 123.2},
+		Fields:  slog.M{"user": "inhere", "uid": 23},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = f.Format(r)
+	}
+}
+
+// the encoding/json based encoding before the hand-written encoder, for compare
+func BenchmarkJSONFormatter_EncodingJSON(b *testing.B) {
+	r := &slog.Record{
+		Time:    time.Now(),
+		Level:   slog.InfoLevel,
+		Channel: "app",
+		Message: msg,
+		Data:    slog.M{"rate": 15, "low": 16, "high": 123.2},
+		Fields:  slog.M{"user": "inhere", "uid": 23},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		logData := slog.M{
+			"datetime": r.Time.Format(slog.DefaultTimeFormat),
+			"channel":  r.Channel,
+			"level":    r.LevelName(),
+			"message":  r.Message,
+			"data":     r.Data,
+			"extra":    r.Extra,
+		}
+		for k, v := range r.Fields {
+			logData[k] = v
+		}
+
+		buf := &bytes.Buffer{}
+		_ = json.NewEncoder(buf).Encode(logData)
+	}
+}
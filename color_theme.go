@@ -0,0 +1,422 @@
+package slog
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gookit/color"
+)
+
+// ColorLevel the color support level of the terminal
+type ColorLevel uint8
+
+// the color levels
+const (
+	ColorLevelNone ColorLevel = iota
+	// ColorLevel16 the basic 16 colors
+	ColorLevel16
+	ColorLevel256
+	// ColorLevelRGB the 24-bit true colors
+	ColorLevelRGB
+)
+
+// DetectColorLevel get the color level of the current terminal, by the gookit/color detection.
+// returns ColorLevelNone on the env NO_COLOR is set.
+func DetectColorLevel() ColorLevel {
+	switch {
+	case !color.Enable || !color.SupportColor():
+		return ColorLevelNone
+	case color.SupportTrueColor():
+		return ColorLevelRGB
+	case color.Support256Color():
+		return ColorLevel256
+	}
+	return ColorLevel16
+}
+
+// ErrThemeColor returns on parse an invalid theme color
+var ErrThemeColor = errors.New("slog: invalid theme color")
+
+// ThemeColor an color of the Theme, parsed from the space separated spec:
+//
+// - the basic colors: "red", "green", "lightBlue", "darkGray" ... see color.FgColors, color.ExFgColors
+// - the 256 colors: "208"
+// - the RGB colors: "#ff8700", "#f80"
+// - the options: "bold", "fuzzy", "italic", "underscore", "blink", "reverse"
+//
+// eg: "bold #ff8700". the 256 and RGB colors are degraded to the color level of the terminal.
+type ThemeColor struct {
+	spec string
+	// the codes for each color level
+	codes [4]string
+}
+
+// NewThemeColor parse the spec to ThemeColor
+func NewThemeColor(spec string) (ThemeColor, error) {
+	c := ThemeColor{spec: spec}
+
+	var opts []string
+	var rgb []uint8
+	c256 := -1
+	basic := ""
+	for _, s := range strings.Fields(spec) {
+		if op, ok := color.AllOptions[s]; ok {
+			opts = append(opts, op.String())
+			continue
+		}
+
+		if fg, ok := color.FgColors[s]; ok {
+			basic = fg.String()
+		} else if fg, ok := color.ExFgColors[s]; ok {
+			basic = fg.String()
+		} else if s == "gray" {
+			basic = color.FgGray.String()
+		} else if s[0] == '#' {
+			vs := color.HexToRgb(s)
+			if len(vs) != 3 {
+				return c, ErrThemeColor
+			}
+			rgb = []uint8{uint8(vs[0]), uint8(vs[1]), uint8(vs[2])}
+		} else if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < 256 {
+			c256 = n
+		} else {
+			return c, ErrThemeColor
+		}
+	}
+
+	// the color codes for each level
+	c16, c256s, crgb := basic, basic, basic
+	switch {
+	case rgb != nil:
+		c16 = strconv.Itoa(int(color.Rgb2basic(rgb[0], rgb[1], rgb[2], false)))
+		c256s = "38;5;" + strconv.Itoa(int(color.RgbTo256(rgb[0], rgb[1], rgb[2])))
+		crgb = "38;2;" + strconv.Itoa(int(rgb[0])) + ";" + strconv.Itoa(int(rgb[1])) + ";" + strconv.Itoa(int(rgb[2]))
+	case c256 >= 0:
+		vs := color.C256ToRgb(uint8(c256))
+		c16 = strconv.Itoa(int(color.Rgb2basic(vs[0], vs[1], vs[2], false)))
+		c256s = "38;5;" + strconv.Itoa(c256)
+		crgb = c256s
+	}
+
+	prefix := strings.Join(opts, ";")
+	for level, code := range [4]string{"", c16, c256s, crgb} {
+		if prefix != "" && code != "" {
+			code = prefix + ";" + code
+		} else if prefix != "" {
+			code = prefix
+		}
+
+		if level > 0 {
+			c.codes[level] = code
+		}
+	}
+	return c, nil
+}
+
+// MustThemeColor parse the spec to ThemeColor, panic on error
+func MustThemeColor(spec string) ThemeColor {
+	c, err := NewThemeColor(spec)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// String get the spec
+func (c ThemeColor) String() string {
+	return c.spec
+}
+
+// IsEmpty check
+func (c ThemeColor) IsEmpty() bool {
+	return c.codes[ColorLevel16] == ""
+}
+
+// Code get the color code for the color level. eg: "1;38;5;208"
+func (c ThemeColor) Code(level ColorLevel) string {
+	if level > ColorLevelRGB {
+		level = ColorLevelRGB
+	}
+	return c.codes[level]
+}
+
+// Render the text by the color of current terminal color level.
+// the level is detected on each call, the formatters resolve it once
+func (c ThemeColor) Render(s string) string {
+	return c.RenderLevel(s, DetectColorLevel())
+}
+
+// RenderLevel render the text by the color of the color level. the ColorLevelNone will output the text as is
+func (c ThemeColor) RenderLevel(s string, level ColorLevel) string {
+	return renderColorCode(c.Code(level), s)
+}
+
+// render the text with the color code. eg: "1;31"
+func renderColorCode(code, s string) string {
+	if code == "" || s == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// resolve the color level of the formatter. use the ColorLevel16 on force and the terminal not support color
+func resolveColorLevel(force bool) ColorLevel {
+	level := DetectColorLevel()
+	if level == ColorLevelNone && force {
+		return ColorLevel16
+	}
+	return level
+}
+
+// MarshalJSON to the spec
+func (c ThemeColor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.spec)
+}
+
+// UnmarshalJSON from the spec
+func (c *ThemeColor) UnmarshalJSON(bs []byte) error {
+	var spec string
+	if err := json.Unmarshal(bs, &spec); err != nil {
+		return err
+	}
+
+	nc, err := NewThemeColor(spec)
+	if err != nil {
+		return err
+	}
+
+	*c = nc
+	return nil
+}
+
+// Theme the colors for each part of the log output. used by the TextFormatter and DevFormatter.
+type Theme struct {
+	Name string
+	// Levels the colors of the level names. the message use the level color on the Message is empty
+	Levels map[Level]ThemeColor
+
+	Time    ThemeColor
+	Channel ThemeColor
+	Caller  ThemeColor
+	Message ThemeColor
+	// Key the field keys
+	Key ThemeColor
+	// the field values by type
+	String ThemeColor
+	Number ThemeColor
+	Bool   ThemeColor
+	Null   ThemeColor
+	Error  ThemeColor
+	// Other the values of the other types, and the Data, Extra
+	Other ThemeColor
+}
+
+// the built-in themes
+var (
+	// DarkTheme for the terminal with dark background
+	DarkTheme = &Theme{
+		Name: "dark",
+		Levels: map[Level]ThemeColor{
+			PanicLevel:  MustThemeColor("bold #ff5f5f"),
+			FatalLevel:  MustThemeColor("bold #ff5f5f"),
+			ErrorLevel:  MustThemeColor("#ff5f5f"),
+			WarnLevel:   MustThemeColor("#ffaf00"),
+			NoticeLevel: MustThemeColor("bold"),
+			InfoLevel:   MustThemeColor("#5fd75f"),
+			DebugLevel:  MustThemeColor("#5fafd7"),
+			TraceLevel:  MustThemeColor("gray"),
+		},
+		Time:    MustThemeColor("gray"),
+		Channel: MustThemeColor("gray"),
+		Caller:  MustThemeColor("#5f87d7"),
+		Key:     MustThemeColor("#5fafaf"),
+		String:  MustThemeColor("#87d787"),
+		Number:  MustThemeColor("#d7af5f"),
+		Bool:    MustThemeColor("#d787d7"),
+		Null:    MustThemeColor("gray"),
+		Error:   MustThemeColor("#ff5f5f"),
+		Other:   MustThemeColor("#87afd7"),
+	}
+
+	// LightTheme for the terminal with light background
+	LightTheme = &Theme{
+		Name: "light",
+		Levels: map[Level]ThemeColor{
+			PanicLevel:  MustThemeColor("bold #d70000"),
+			FatalLevel:  MustThemeColor("bold #d70000"),
+			ErrorLevel:  MustThemeColor("#d70000"),
+			WarnLevel:   MustThemeColor("#af5f00"),
+			NoticeLevel: MustThemeColor("bold"),
+			InfoLevel:   MustThemeColor("#008700"),
+			DebugLevel:  MustThemeColor("#005f87"),
+			TraceLevel:  MustThemeColor("244"),
+		},
+		Time:    MustThemeColor("244"),
+		Channel: MustThemeColor("244"),
+		Caller:  MustThemeColor("#005faf"),
+		Key:     MustThemeColor("#008787"),
+		String:  MustThemeColor("#005f00"),
+		Number:  MustThemeColor("#875f00"),
+		Bool:    MustThemeColor("#870087"),
+		Null:    MustThemeColor("244"),
+		Error:   MustThemeColor("#d70000"),
+		Other:   MustThemeColor("#005f87"),
+	}
+
+	// Themes the registered themes by name. the base of the theme file is from it.
+	Themes = map[string]*Theme{
+		"dark":  DarkTheme,
+		"light": LightTheme,
+	}
+)
+
+// LevelColor get the color of the level
+func (t *Theme) LevelColor(level Level) ThemeColor {
+	return t.Levels[level]
+}
+
+// ValueColor get the color by the value type
+func (t *Theme) ValueColor(val interface{}) ThemeColor {
+	switch val.(type) {
+	case nil:
+		return t.Null
+	case string:
+		return t.String
+	case bool:
+		return t.Bool
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return t.Number
+	case error:
+		return t.Error
+	}
+	return t.Other
+}
+
+// render the text of the field by the field name, or the value type for the custom fields
+func (t *Theme) renderField(field string, val interface{}, s string, level Level, cl ColorLevel) string {
+	var c ThemeColor
+	switch field {
+	case FieldKeyDatetime, FieldKeyTimestamp:
+		c = t.Time
+	case FieldKeyLevel:
+		c = t.Levels[level]
+	case FieldKeyMessage:
+		c = t.Message
+		if c.IsEmpty() {
+			c = t.Levels[level]
+		}
+	case FieldKeyChannel:
+		c = t.Channel
+	case FieldKeyCaller, FieldKeyFLine, FieldKeyFile, FieldKeyFunc, FieldKeyFcName:
+		c = t.Caller
+	default:
+		c = t.ValueColor(val)
+	}
+	return c.RenderLevel(s, cl)
+}
+
+// Clone the theme
+func (t *Theme) Clone() *Theme {
+	nt := *t
+	nt.Levels = make(map[Level]ThemeColor, len(t.Levels))
+	for level, c := range t.Levels {
+		nt.Levels[level] = c
+	}
+	return &nt
+}
+
+// the theme file content. the unset colors are from the base theme
+type themeConfig struct {
+	Name string `json:"name"`
+	// Base the name of the base theme. default is "dark"
+	Base string `json:"base"`
+	// Levels the colors by level name. eg: {"error": "bold red"}
+	Levels map[string]ThemeColor `json:"levels"`
+
+	Time    *ThemeColor `json:"time"`
+	Channel *ThemeColor `json:"channel"`
+	Caller  *ThemeColor `json:"caller"`
+	Message *ThemeColor `json:"message"`
+	Key     *ThemeColor `json:"key"`
+	String  *ThemeColor `json:"string"`
+	Number  *ThemeColor `json:"number"`
+	Bool    *ThemeColor `json:"bool"`
+	Null    *ThemeColor `json:"null"`
+	Error   *ThemeColor `json:"error"`
+	Other   *ThemeColor `json:"other"`
+}
+
+// LoadTheme load the theme from JSON. the unset colors are from the base theme.
+//
+// eg:
+// 	{
+// 		"name": "mine",
+// 		"base": "light",
+// 		"levels": {"error": "bold #ff0000", "info": "green"},
+// 		"time": "244",
+// 		"caller": "underscore blue"
+// 	}
+func LoadTheme(r io.Reader) (*Theme, error) {
+	var cfg themeConfig
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.Base == "" {
+		cfg.Base = "dark"
+	}
+
+	base, ok := Themes[cfg.Base]
+	if !ok {
+		return nil, errors.New("slog: theme not found: " + cfg.Base)
+	}
+
+	t := base.Clone()
+	if cfg.Name != "" {
+		t.Name = cfg.Name
+	}
+	for name, c := range cfg.Levels {
+		level, err := Name2Level(name)
+		if err != nil {
+			return nil, err
+		}
+		t.Levels[level] = c
+	}
+
+	for _, item := range []struct {
+		dst *ThemeColor
+		src *ThemeColor
+	}{
+		{&t.Time, cfg.Time},
+		{&t.Channel, cfg.Channel},
+		{&t.Caller, cfg.Caller},
+		{&t.Message, cfg.Message},
+		{&t.Key, cfg.Key},
+		{&t.String, cfg.String},
+		{&t.Number, cfg.Number},
+		{&t.Bool, cfg.Bool},
+		{&t.Null, cfg.Null},
+		{&t.Error, cfg.Error},
+		{&t.Other, cfg.Other},
+	} {
+		if item.src != nil {
+			*item.dst = *item.src
+		}
+	}
+	return t, nil
+}
+
+// LoadThemeFile load the theme from the JSON file. see LoadTheme()
+func LoadThemeFile(file string) (*Theme, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	return LoadTheme(fh)
+}
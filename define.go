@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 )
 
 // StringMap string map short name
@@ -35,6 +36,95 @@ func (l Level) LowerName() string {
 	return strings.ToLower(LevelName(l))
 }
 
+// SyslogSeverity get the syslog severity(RFC 5424) of the level.
+//
+// Panic:0(Emergency), Fatal:2(Critical), Error:3(Error), Warn:4(Warning),
+// Notice:5(Notice), Info:6(Informational), Debug,Trace:7(Debug)
+func (l Level) SyslogSeverity() int {
+	switch {
+	case l <= PanicLevel:
+		return 0
+	case l <= FatalLevel:
+		return 2
+	case l <= ErrorLevel:
+		return 3
+	case l <= WarnLevel:
+		return 4
+	case l <= NoticeLevel:
+		return 5
+	case l <= InfoLevel:
+		return 6
+	}
+	return 7
+}
+
+// OTelSeverity get the OpenTelemetry SeverityNumber of the level.
+//
+// Trace:1, Debug:5, Info:9, Notice:10, Warn:13, Error:17, Fatal:21, Panic:24
+func (l Level) OTelSeverity() int {
+	switch {
+	case l <= PanicLevel:
+		return 24
+	case l <= FatalLevel:
+		return 21
+	case l <= ErrorLevel:
+		return 17
+	case l <= WarnLevel:
+		return 13
+	case l <= NoticeLevel:
+		return 10
+	case l <= InfoLevel:
+		return 9
+	case l <= DebugLevel:
+		return 5
+	}
+	return 1
+}
+
+// CEFSeverity get the CEF/LEEF severity(0-10) of the level.
+//
+// Panic:10, Fatal:9, Error:7, Warn:5, Notice:4, Info:3, Debug:1, Trace:0
+func (l Level) CEFSeverity() int {
+	switch {
+	case l <= PanicLevel:
+		return 10
+	case l <= FatalLevel:
+		return 9
+	case l <= ErrorLevel:
+		return 7
+	case l <= WarnLevel:
+		return 5
+	case l <= NoticeLevel:
+		return 4
+	case l <= InfoLevel:
+		return 3
+	case l <= DebugLevel:
+		return 1
+	}
+	return 0
+}
+
+// GCPSeverity get the Google Cloud Logging LogSeverity name of the level.
+//
+// Panic:EMERGENCY, Fatal:CRITICAL, Error:ERROR, Warn:WARNING, Notice:NOTICE, Info:INFO, Debug,Trace:DEBUG
+func (l Level) GCPSeverity() string {
+	switch {
+	case l <= PanicLevel:
+		return "EMERGENCY"
+	case l <= FatalLevel:
+		return "CRITICAL"
+	case l <= ErrorLevel:
+		return "ERROR"
+	case l <= WarnLevel:
+		return "WARNING"
+	case l <= NoticeLevel:
+		return "NOTICE"
+	case l <= InfoLevel:
+		return "INFO"
+	}
+	return "DEBUG"
+}
+
 // ShouldHandling compare level
 func (l Level) ShouldHandling(curLevel Level) bool {
 	return curLevel <= l
@@ -89,6 +179,36 @@ type Handler interface {
 	Handle(*Record) error
 }
 
+// ErrorPolicy on a handler returns error
+type ErrorPolicy uint8
+
+const (
+	// ErrPolicyContinue continue to handle the record by remaining handlers. it is default policy.
+	ErrPolicyContinue ErrorPolicy = iota
+	// ErrPolicyStop stop to handle the record, the remaining handlers will not see the record.
+	ErrPolicyStop
+	// ErrPolicyRetry retry the failed handler up to Logger.MaxRetries times, then continue.
+	// the retries are wait by Logger.RetryWait on the caller goroutine, so it is for the transient errors.
+	// for the network handlers, please use the handler.RetryHandler.
+	ErrPolicyRetry
+)
+
+// String get policy name
+func (p ErrorPolicy) String() string {
+	switch p {
+	case ErrPolicyContinue:
+		return "continue"
+	case ErrPolicyStop:
+		return "stop"
+	case ErrPolicyRetry:
+		return "retry"
+	}
+	return "unknown"
+}
+
+// ErrorHandler will be called on an handler returns error
+type ErrorHandler func(h Handler, r *Record, err error)
+
 //
 // Processor interface
 //
@@ -237,6 +357,16 @@ var (
 	FieldKeyMessage = "message"
 )
 
+var (
+	// ErrorsChannel the channel name of the internal records that reporting handler errors.
+	// see Logger.FallbackHandler
+	ErrorsChannel = "slog.errors"
+	// DefaultMaxRetries for the ErrPolicyRetry
+	DefaultMaxRetries = 2
+	// DefaultRetryWait the wait time before the first retry of the ErrPolicyRetry
+	DefaultRetryWait = 10 * time.Millisecond
+)
+
 var (
 	DefaultChannelName = "application"
 	DefaultTimeFormat  = "2006/01/02 15:04:05"
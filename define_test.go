@@ -41,3 +41,25 @@ func TestLevel_ShouldHandling(t *testing.T) {
 	assert.True(t, slog.DebugLevel.ShouldHandling(slog.InfoLevel))
 	assert.False(t, slog.DebugLevel.ShouldHandling(slog.TraceLevel))
 }
+
+func TestLevel_SyslogSeverity(t *testing.T) {
+	assert.Equal(t, 0, slog.PanicLevel.SyslogSeverity())
+	assert.Equal(t, 2, slog.FatalLevel.SyslogSeverity())
+	assert.Equal(t, 3, slog.ErrorLevel.SyslogSeverity())
+	assert.Equal(t, 4, slog.WarnLevel.SyslogSeverity())
+	assert.Equal(t, 5, slog.NoticeLevel.SyslogSeverity())
+	assert.Equal(t, 6, slog.InfoLevel.SyslogSeverity())
+	assert.Equal(t, 7, slog.DebugLevel.SyslogSeverity())
+	assert.Equal(t, 7, slog.TraceLevel.SyslogSeverity())
+}
+
+func TestLevel_OTelSeverity(t *testing.T) {
+	assert.Equal(t, 24, slog.PanicLevel.OTelSeverity())
+	assert.Equal(t, 21, slog.FatalLevel.OTelSeverity())
+	assert.Equal(t, 17, slog.ErrorLevel.OTelSeverity())
+	assert.Equal(t, 13, slog.WarnLevel.OTelSeverity())
+	assert.Equal(t, 10, slog.NoticeLevel.OTelSeverity())
+	assert.Equal(t, 9, slog.InfoLevel.OTelSeverity())
+	assert.Equal(t, 5, slog.DebugLevel.OTelSeverity())
+	assert.Equal(t, 1, slog.TraceLevel.OTelSeverity())
+}
@@ -0,0 +1,281 @@
+package slog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/tomorrowsky/slog/internal/cbor"
+	"github.com/tomorrowsky/slog/internal/msgpack"
+)
+
+// MaxBinaryRecordSize the max size of an framed record for decode. default is 64MB
+var MaxBinaryRecordSize = 64 << 20
+
+// ErrBinaryRecord returns on decode an invalid binary record
+var ErrBinaryRecord = errors.New("slog: invalid binary record")
+
+// the encode funcs of an binary format
+type binaryCodec struct {
+	mapHeader func(b []byte, n int) []byte
+	str       func(b []byte, s string) []byte
+	int       func(b []byte, v int64) []byte
+	time      func(b []byte, t time.Time) []byte
+	value     func(b []byte, v interface{}) []byte
+}
+
+var msgpackCodec = binaryCodec{
+	mapHeader: msgpack.AppendMapHeader,
+	str:       msgpack.AppendString,
+	int:       msgpack.AppendInt,
+	time:      msgpack.AppendTimestamp,
+	value:     msgpack.AppendValue,
+}
+
+var cborCodec = binaryCodec{
+	mapHeader: cbor.AppendMapHeader,
+	str:       cbor.AppendString,
+	int:       cbor.AppendInt,
+	time:      cbor.AppendTime,
+	value:     cbor.AppendValue,
+}
+
+// encode the record as an map:
+//
+// 	{"time": timestamp, "level": 300, "channel": "app", "message": "msg",
+// 	"caller": {"file": "main.go", "line": 12, "func": "main.main"}, "data": {}, "extra": {}, "fields": {}}
+//
+// the empty channel, caller, data, extra and fields are omitted.
+func appendBinaryRecord(b []byte, r *Record, c binaryCodec, framed bool) []byte {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	start := len(b)
+	if framed {
+		// reserve for the length
+		b = append(b, 0, 0, 0, 0)
+	}
+
+	n := 3
+	for _, ok := range []bool{r.Channel != "", r.Caller != nil, len(r.Data) > 0, len(r.Extra) > 0, len(r.Fields) > 0} {
+		if ok {
+			n++
+		}
+	}
+
+	b = c.mapHeader(b, n)
+	b = c.time(c.str(b, FieldKeyTime), r.Time)
+	b = c.int(c.str(b, FieldKeyLevel), int64(r.Level))
+	b = c.str(c.str(b, FieldKeyMessage), r.Message)
+
+	if r.Channel != "" {
+		b = c.str(c.str(b, FieldKeyChannel), r.Channel)
+	}
+	if r.Caller != nil {
+		b = c.mapHeader(c.str(b, FieldKeyCaller), 3)
+		b = c.str(c.str(b, "file"), r.Caller.File)
+		b = c.int(c.str(b, "line"), int64(r.Caller.Line))
+		b = c.str(c.str(b, "func"), r.Caller.Function)
+	}
+	if len(r.Data) > 0 {
+		b = c.value(c.str(b, FieldKeyData), map[string]interface{}(r.Data))
+	}
+	if len(r.Extra) > 0 {
+		b = c.value(c.str(b, FieldKeyExtra), map[string]interface{}(r.Extra))
+	}
+	if len(r.Fields) > 0 {
+		b = c.value(c.str(b, "fields"), map[string]interface{}(r.Fields))
+	}
+
+	if framed {
+		binary.BigEndian.PutUint32(b[start:], uint32(len(b)-start-4))
+	}
+	return b
+}
+
+// MsgpackFormatter definition. encode the record to an MessagePack map.
+//
+// - the time is encoded as the msgpack timestamp extension(type -1), the level as an integer
+// - the Data, Extra and Fields are encoded as the nested maps. the time values in them are RFC3339 strings
+// - with Framed, each record is prefixed with its length as 4 bytes big-endian uint32
+//
+// Use the NewMsgpackDecoder() for read the records back.
+type MsgpackFormatter struct {
+	// Framed prefix the length for each record. default is true
+	Framed bool
+}
+
+// NewMsgpackFormatter create new MsgpackFormatter
+func NewMsgpackFormatter(fn ...func(*MsgpackFormatter)) *MsgpackFormatter {
+	f := &MsgpackFormatter{Framed: true}
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Format an log record
+func (f *MsgpackFormatter) Format(r *Record) ([]byte, error) {
+	return appendBinaryRecord(make([]byte, 0, 256), r, msgpackCodec, f.Framed), nil
+}
+
+// CBORFormatter definition. encode the record to an CBOR map.
+//
+// - the time is encoded as the epoch-based date/time(tag 1), the level as an integer
+// - the Data, Extra and Fields are encoded as the nested maps
+// - with Framed, each record is prefixed with its length as 4 bytes big-endian uint32
+//
+// Use the NewCBORDecoder() for read the records back.
+//
+// NOTICE: the time with fractional seconds is encoded as an float64, has the microseconds precision.
+type CBORFormatter struct {
+	// Framed prefix the length for each record. default is true
+	Framed bool
+}
+
+// NewCBORFormatter create new CBORFormatter
+func NewCBORFormatter(fn ...func(*CBORFormatter)) *CBORFormatter {
+	f := &CBORFormatter{Framed: true}
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Format an log record
+func (f *CBORFormatter) Format(r *Record) ([]byte, error) {
+	return appendBinaryRecord(make([]byte, 0, 256), r, cborCodec, f.Framed), nil
+}
+
+// RecordDecoder read the records encoded by the MsgpackFormatter or CBORFormatter.
+type RecordDecoder struct {
+	// Framed the records are length-prefixed. default is true
+	Framed bool
+
+	r *bufio.Reader
+	// decode an value from the data, or the stream on data is nil
+	decode func(data []byte) (interface{}, error)
+}
+
+// NewMsgpackDecoder create new RecordDecoder for the MsgpackFormatter output.
+//
+// Usage:
+// 	dec := slog.NewMsgpackDecoder(file)
+// 	for {
+// 		r, err := dec.Decode()
+// 		if err == io.EOF {
+// 			break
+// 		}
+// 		// ...
+// 	}
+func NewMsgpackDecoder(r io.Reader) *RecordDecoder {
+	d := &RecordDecoder{Framed: true, r: bufio.NewReader(r)}
+	d.decode = func(data []byte) (interface{}, error) {
+		if data != nil {
+			return msgpack.Unmarshal(data)
+		}
+		return msgpack.NewDecoder(d.r).Decode()
+	}
+	return d
+}
+
+// NewCBORDecoder create new RecordDecoder for the CBORFormatter output. see NewMsgpackDecoder()
+func NewCBORDecoder(r io.Reader) *RecordDecoder {
+	d := &RecordDecoder{Framed: true, r: bufio.NewReader(r)}
+	d.decode = func(data []byte) (interface{}, error) {
+		if data != nil {
+			return cbor.Unmarshal(data)
+		}
+		return cbor.NewDecoder(d.r).Decode()
+	}
+	return d
+}
+
+// Decode read the next record. return io.EOF on no more records.
+func (d *RecordDecoder) Decode() (*Record, error) {
+	var data []byte
+	if d.Framed {
+		head := make([]byte, 4)
+		if _, err := io.ReadFull(d.r, head); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return nil, ErrBinaryRecord
+			}
+			return nil, err
+		}
+
+		size := binary.BigEndian.Uint32(head)
+		if uint64(size) > uint64(MaxBinaryRecordSize) {
+			return nil, ErrBinaryRecord
+		}
+
+		// the buffer is grown by the read data, an corrupt size will not alloc the memory before read
+		prealloc := int(size)
+		if prealloc > 64<<10 {
+			prealloc = 64 << 10
+		}
+
+		buf := bytes.NewBuffer(make([]byte, 0, prealloc))
+		if _, err := io.CopyN(buf, d.r, int64(size)); err != nil {
+			return nil, ErrBinaryRecord
+		}
+		data = buf.Bytes()
+	}
+
+	v, err := d.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	mp, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, ErrBinaryRecord
+	}
+	return recordFromMap(mp)
+}
+
+// build the record from the decoded map
+func recordFromMap(mp map[string]interface{}) (*Record, error) {
+	r := &Record{}
+	switch tv := mp[FieldKeyTime].(type) {
+	case time.Time:
+		r.Time = tv
+	case msgpack.Ext:
+		r.Time, _ = tv.Timestamp()
+	}
+
+	lv, ok := mp[FieldKeyLevel].(int64)
+	if !ok || r.Time.IsZero() {
+		return nil, ErrBinaryRecord
+	}
+
+	r.Level = Level(lv)
+	r.Message, _ = mp[FieldKeyMessage].(string)
+	r.Channel, _ = mp[FieldKeyChannel].(string)
+
+	if caller, ok := mp[FieldKeyCaller].(map[string]interface{}); ok {
+		r.Caller = &runtime.Frame{}
+		r.Caller.File, _ = caller["file"].(string)
+		r.Caller.Function, _ = caller["func"].(string)
+		if line, ok := caller["line"].(int64); ok {
+			r.Caller.Line = int(line)
+		}
+	}
+
+	if data, ok := mp[FieldKeyData].(map[string]interface{}); ok {
+		r.Data = data
+	}
+	if extra, ok := mp[FieldKeyExtra].(map[string]interface{}); ok {
+		r.Extra = extra
+	}
+	if fields, ok := mp["fields"].(map[string]interface{}); ok {
+		r.Fields = fields
+	}
+	return r, nil
+}
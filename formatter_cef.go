@@ -0,0 +1,233 @@
+package slog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// escape the header field of CEF and LEEF
+	cefHeaderReplacer = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	// escape the extension value of CEF
+	cefValueReplacer = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+)
+
+// CEFFormatter definition. format the record to an ArcSight Common Event Format(CEF) event.
+//
+// eg:
+// 	CEF:0|MyCompany|MyApp|1.0|user.login|user login|3|rt=1614571200000 cat=application uid=23
+//
+// - the signature ID is from the Record.Fields[SignatureField], or the record channel
+// - the name is the record message, severity is mapped from the level. see Level.CEFSeverity()
+// - the extension has rt(the time in milliseconds), cat(the channel), then the Data and Fields
+// sorted by key. the nested maps are flattened to dotted keys
+type CEFFormatter struct {
+	// Vendor the device vendor
+	Vendor string
+	// Product the device product
+	Product string
+	// Version the device version
+	Version string
+	// SignatureField the key of Record.Fields for the signature ID. default is "signature_id"
+	SignatureField string
+}
+
+// NewCEFFormatter create new CEFFormatter
+func NewCEFFormatter(fn ...func(*CEFFormatter)) *CEFFormatter {
+	f := &CEFFormatter{
+		Vendor:         "slog",
+		Product:        "slog",
+		Version:        "1.0",
+		SignatureField: "signature_id",
+	}
+
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Configure current formatter
+func (f *CEFFormatter) Configure(fn func(*CEFFormatter)) *CEFFormatter {
+	fn(f)
+	return f
+}
+
+// Format an log record to CEF event
+func (f *CEFFormatter) Format(r *Record) ([]byte, error) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	buf := make([]byte, 0, 256)
+	buf = append(buf, "CEF:0|"...)
+	for _, field := range []string{f.Vendor, f.Product, f.Version, signatureID(r, f.SignatureField), r.Message} {
+		buf = append(buf, cefHeaderReplacer.Replace(field)...)
+		buf = append(buf, '|')
+	}
+	buf = strconv.AppendInt(buf, int64(r.Level.CEFSeverity()), 10)
+	buf = append(buf, '|')
+
+	buf = append(buf, "rt="...)
+	buf = strconv.AppendInt(buf, r.Time.UnixNano()/int64(time.Millisecond), 10)
+	if r.Channel != "" {
+		buf = append(buf, " cat="...)
+		buf = append(buf, cefValueReplacer.Replace(r.Channel)...)
+	}
+
+	eachEventField(r, f.SignatureField, func(key, val string) {
+		buf = append(buf, ' ')
+		buf = appendEventKey(buf, key)
+		buf = append(buf, '=')
+		buf = append(buf, cefValueReplacer.Replace(val)...)
+	})
+	return append(buf, '\n'), nil
+}
+
+// LEEFFormatter definition. format the record to an IBM QRadar Log Event Extended Format(LEEF 2.0) event.
+//
+// eg:
+// 	LEEF:2.0|MyCompany|MyApp|1.0|user.login|x09|devTime=Mar 01 2021 12:00:00.000 UTC	devTimeFormat=MMM dd yyyy HH:mm:ss.SSS z	sev=3	cat=application	msg=user login	uid=23
+//
+// - the event ID is from the Record.Fields[EventIDField], or the record channel
+// - the attributes has devTime, sev(mapped from the level), cat(the channel), msg, then the Data and Fields
+// sorted by key. the nested maps are flattened to dotted keys
+// - the attributes are separated by the Delimiter. the backslash, delimiter and newlines in the values are escaped
+type LEEFFormatter struct {
+	// Vendor the device vendor
+	Vendor string
+	// Product the device product
+	Product string
+	// Version the device version
+	Version string
+	// EventIDField the key of Record.Fields for the event ID. default is "event_id"
+	EventIDField string
+	// Delimiter for separate the attributes. default is '\t'
+	Delimiter byte
+}
+
+// NewLEEFFormatter create new LEEFFormatter
+func NewLEEFFormatter(fn ...func(*LEEFFormatter)) *LEEFFormatter {
+	f := &LEEFFormatter{
+		Vendor:       "slog",
+		Product:      "slog",
+		Version:      "1.0",
+		EventIDField: "event_id",
+		Delimiter:    '\t',
+	}
+
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Configure current formatter
+func (f *LEEFFormatter) Configure(fn func(*LEEFFormatter)) *LEEFFormatter {
+	fn(f)
+	return f
+}
+
+// Format an log record to LEEF event
+func (f *LEEFFormatter) Format(r *Record) ([]byte, error) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	buf := make([]byte, 0, 256)
+	buf = append(buf, "LEEF:2.0|"...)
+	for _, field := range []string{f.Vendor, f.Product, f.Version, signatureID(r, f.EventIDField)} {
+		buf = append(buf, cefHeaderReplacer.Replace(field)...)
+		buf = append(buf, '|')
+	}
+
+	// the printable delimiter is written as is, others as the hex. eg: "x09"
+	if f.Delimiter > 32 && f.Delimiter < 127 && f.Delimiter != '|' {
+		buf = append(buf, f.Delimiter)
+	} else {
+		buf = append(buf, 'x')
+		buf = append(buf, "0123456789abcdef"[f.Delimiter>>4], "0123456789abcdef"[f.Delimiter&0x0f])
+	}
+	buf = append(buf, '|')
+
+	valueReplacer := strings.NewReplacer(`\`, `\\`, string(f.Delimiter), `\`+string(f.Delimiter), "\r", `\r`, "\n", `\n`)
+	first := true
+	appendAttr := func(key, val string) {
+		if !first {
+			buf = append(buf, f.Delimiter)
+		}
+		first = false
+
+		buf = appendEventKey(buf, key)
+		buf = append(buf, '=')
+		buf = append(buf, valueReplacer.Replace(val)...)
+	}
+
+	appendAttr("devTime", r.Time.Format("Jan 02 2006 15:04:05.000 MST"))
+	appendAttr("devTimeFormat", "MMM dd yyyy HH:mm:ss.SSS z")
+	appendAttr("sev", strconv.Itoa(r.Level.CEFSeverity()))
+	if r.Channel != "" {
+		appendAttr("cat", r.Channel)
+	}
+	appendAttr("msg", r.Message)
+
+	eachEventField(r, f.EventIDField, appendAttr)
+	return append(buf, '\n'), nil
+}
+
+// get the signature/event ID from the fields, or the channel
+func signatureID(r *Record, field string) string {
+	if v, ok := r.Fields[field]; ok && field != "" {
+		return EncodeToString(v)
+	}
+
+	if r.Channel != "" {
+		return r.Channel
+	}
+	return DefaultChannelName
+}
+
+// iterate the Data and Fields(the later will override the former) sorted by key, except the skip key.
+// the nested maps are flattened to dotted keys.
+func eachEventField(r *Record, skip string, fn func(key, val string)) {
+	mp := make(M, len(r.Data)+len(r.Fields))
+	for k, v := range r.Data {
+		mp[k] = v
+	}
+	for k, v := range r.Fields {
+		if k != skip {
+			mp[k] = v
+		}
+	}
+
+	eachFlattenField("", mp, fn)
+}
+
+func eachFlattenField(prefix string, mp map[string]interface{}, fn func(key, val string)) {
+	for _, k := range sortedMapKeys(mp) {
+		switch tv := mp[k].(type) {
+		case M:
+			eachFlattenField(prefix+k+".", tv, fn)
+		case map[string]interface{}:
+			eachFlattenField(prefix+k+".", tv, fn)
+		default:
+			fn(prefix+k, EncodeToString(tv))
+		}
+	}
+}
+
+// the key only contains the letters, digits, '_' and '.', others will be replaced to '_'
+func appendEventKey(buf []byte, key string) []byte {
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, '_')
+		}
+	}
+	return buf
+}
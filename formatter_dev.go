@@ -0,0 +1,332 @@
+package slog
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gookit/color"
+)
+
+// DevFormatter definition. format the record to the pretty multi-line output for the terminal in development.
+//
+// eg:
+// 	+12ms        INFO    app        user login                       slog/main.go:23
+// 	                                uid   = 23
+// 	                                error = not found
+// 	                                        main.main
+// 	                                        	/app/main.go:23
+//
+// - the time is relative to the last record(the first record is the clock time), see RelativeTime
+// - each field is on its own line, the keys are aligned and the values are colored by type.
+// the Fields are by the insertion order, then the Data and Extra as the nested maps
+// - the multi-line messages, values and the error stack traces(by "%+v") are indented, the long lines are wrapped
+// - the long caller path is shortened, the caller is right aligned on the first line if it fits
+// - the width is from the env COLUMNS or the terminal size on the first Format, see Width
+//
+// The color is enabled on the terminal support color, and respect the env NO_COLOR, FORCE_COLOR
+// by the gookit/color detection. the colors are from the Theme.
+type DevFormatter struct {
+	// TimeFormat the clock time layout for the first record, or on RelativeTime is false. default is "15:04:05.000"
+	TimeFormat string
+	// RelativeTime show the time since the last record. eg: "+12ms". default is true
+	RelativeTime bool
+	// Width the max width of the output, 0 for detect by the env COLUMNS or the terminal size(default 100).
+	// the width is detected once on the first Format
+	Width int
+	// ChannelWidth the min width of the channel column. default is 10
+	ChannelWidth int
+	// MaxCallerWidth the max width of the caller, the long path will be shortened. default is 40
+	MaxCallerWidth int
+	// EnableColor render the colors, the color level is detected once on the first Format. default is color.SupportColor()
+	EnableColor bool
+	// ForceColor render the colors on EnableColor, even the output is not a terminal or the env NO_COLOR is set.
+	// the 16 colors are used on the terminal not support color
+	ForceColor bool
+	// Theme the colors for each part. default is the DarkTheme
+	Theme *Theme
+
+	mu sync.Mutex
+	// the time of the last record
+	last time.Time
+
+	widthOnce sync.Once
+	// the detected width on the Width is 0
+	detected int
+
+	colorOnce sync.Once
+	// the color level on EnableColor
+	colorLevel ColorLevel
+}
+
+// NewDevFormatter create new DevFormatter
+func NewDevFormatter(fn ...func(*DevFormatter)) *DevFormatter {
+	f := &DevFormatter{
+		TimeFormat:     "15:04:05.000",
+		RelativeTime:   true,
+		ChannelWidth:   10,
+		MaxCallerWidth: 40,
+		EnableColor:    color.SupportColor(),
+		Theme:          DarkTheme,
+	}
+
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Configure current formatter
+func (f *DevFormatter) Configure(fn func(*DevFormatter)) *DevFormatter {
+	fn(f)
+	return f
+}
+
+// Format an log record
+func (f *DevFormatter) Format(r *Record) ([]byte, error) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	width := f.width()
+	buf := make([]byte, 0, 256)
+
+	// the header columns: time, level, channel
+	timeStr := f.timeString(r.Time)
+	buf = append(buf, f.paint(f.Theme.Time, padRight(timeStr, 12))...)
+	buf = append(buf, ' ')
+	buf = append(buf, f.paint(f.Theme.LevelColor(r.Level), padRight(r.LevelName(), 7))...)
+	buf = append(buf, ' ')
+
+	chWidth := f.ChannelWidth
+	if n := utf8.RuneCountInString(r.Channel); n > chWidth {
+		chWidth = n
+	}
+	buf = append(buf, f.paint(f.Theme.Channel, padRight(r.Channel, chWidth))...)
+	buf = append(buf, ' ')
+
+	// the message column
+	indent := 12 + 1 + 7 + 1 + chWidth + 1
+	lines := wrapLines(r.Message, width-indent)
+	buf = append(buf, f.paint(f.Theme.Message, lines[0])...)
+
+	prefix := strings.Repeat(" ", indent)
+	if r.Caller != nil {
+		caller := shortenCaller(r.Caller.File, r.Caller.Line, f.MaxCallerWidth)
+		used := indent + utf8.RuneCountInString(lines[0])
+		if pad := width - used - utf8.RuneCountInString(caller); pad > 0 {
+			buf = append(buf, strings.Repeat(" ", pad)...)
+		} else {
+			// on next line
+			buf = append(buf, '\n')
+			buf = append(buf, prefix...)
+		}
+		buf = append(buf, f.paint(f.Theme.Caller, caller)...)
+	}
+
+	for _, line := range lines[1:] {
+		buf = append(buf, '\n')
+		buf = append(buf, prefix...)
+		buf = append(buf, f.paint(f.Theme.Message, line)...)
+	}
+
+	// the fields on their own lines
+	mp := make(M, len(r.Fields)+2)
+	keys := make([]string, 0, len(r.Fields)+2)
+	for _, k := range r.FieldKeys() {
+		mp[k] = r.Fields[k]
+		keys = append(keys, k)
+	}
+	if len(r.Data) > 0 {
+		mp[FieldKeyData] = r.Data
+		keys = append(keys, FieldKeyData)
+	}
+	if len(r.Extra) > 0 {
+		mp[FieldKeyExtra] = r.Extra
+		keys = append(keys, FieldKeyExtra)
+	}
+
+	buf = f.appendFields(buf, prefix, keys, mp, width)
+	return append(buf, '\n'), nil
+}
+
+// append each field on its own line, the nested maps are indented
+func (f *DevFormatter) appendFields(buf []byte, prefix string, keys []string, mp map[string]interface{}, width int) []byte {
+	keyWidth := 0
+	for _, k := range keys {
+		if n := utf8.RuneCountInString(k); n > keyWidth {
+			keyWidth = n
+		}
+	}
+
+	for _, k := range keys {
+		buf = append(buf, '\n')
+		buf = append(buf, prefix...)
+
+		switch tv := mp[k].(type) {
+		case M:
+			buf = append(buf, f.paint(f.Theme.Key, k+":")...)
+			buf = f.appendFields(buf, prefix+"  ", sortedMapKeys(tv), tv, width)
+			continue
+		case map[string]interface{}:
+			buf = append(buf, f.paint(f.Theme.Key, k+":")...)
+			buf = f.appendFields(buf, prefix+"  ", sortedMapKeys(tv), tv, width)
+			continue
+		}
+
+		buf = append(buf, f.paint(f.Theme.Key, padRight(k, keyWidth))...)
+		buf = append(buf, " = "...)
+
+		// the value column
+		valPrefix := prefix + strings.Repeat(" ", keyWidth+3)
+		val, c := f.valueString(mp[k])
+		for i, line := range wrapLines(val, width-len(valPrefix)) {
+			if i > 0 {
+				buf = append(buf, '\n')
+				buf = append(buf, valPrefix...)
+			}
+			buf = append(buf, f.paint(c, line)...)
+		}
+	}
+	return buf
+}
+
+// the string and color of the value
+func (f *DevFormatter) valueString(val interface{}) (string, ThemeColor) {
+	c := f.Theme.ValueColor(val)
+	switch tv := val.(type) {
+	case nil:
+		return "null", c
+	case string:
+		if strings.ContainsRune(tv, '\n') || !needsQuote(tv) {
+			return tv, c
+		}
+		return strconv.Quote(tv), c
+	case time.Time:
+		return tv.Format(time.RFC3339Nano), c
+	case error:
+		// the error with stack. eg: from github.com/pkg/errors
+		return fmt.Sprintf("%+v", tv), c
+	}
+
+	return EncodeToString(val), c
+}
+
+// the time since the last record, or the clock time
+func (f *DevFormatter) timeString(t time.Time) string {
+	if !f.RelativeTime {
+		return t.Format(f.TimeFormat)
+	}
+
+	f.mu.Lock()
+	last := f.last
+	f.last = t
+	f.mu.Unlock()
+
+	if last.IsZero() {
+		return t.Format(f.TimeFormat)
+	}
+
+	d := t.Sub(last)
+	switch {
+	case d < 0:
+		return "+0ms"
+	case d < time.Second:
+		return "+" + strconv.FormatInt(int64(d/time.Millisecond), 10) + "ms"
+	case d < time.Minute:
+		return "+" + strconv.FormatFloat(d.Seconds(), 'f', 2, 64) + "s"
+	}
+	return "+" + d.Truncate(time.Second).String()
+}
+
+// get the output width
+func (f *DevFormatter) width() int {
+	if f.Width > 0 {
+		return f.Width
+	}
+
+	f.widthOnce.Do(func() {
+		f.detected = detectWidth()
+	})
+	return f.detected
+}
+
+// detect the output width by the env COLUMNS or the terminal size
+func detectWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if n := terminalColumns(); n > 0 {
+		return n
+	}
+	return 100
+}
+
+func (f *DevFormatter) paint(c ThemeColor, s string) string {
+	if !f.EnableColor {
+		return s
+	}
+
+	f.colorOnce.Do(func() {
+		f.colorLevel = resolveColorLevel(f.ForceColor)
+	})
+	return c.RenderLevel(s, f.colorLevel)
+}
+
+// shorten the caller path to max width. eg: "/work/go/src/app/handler/user.go:48" -> "…/handler/user.go:48"
+func shortenCaller(file string, line int, max int) string {
+	dir, name := path.Split(file)
+	caller := path.Base(dir) + "/" + name + ":" + strconv.Itoa(line)
+	if dir == "" {
+		caller = name + ":" + strconv.Itoa(line)
+	}
+
+	if max <= 1 || utf8.RuneCountInString(caller) <= max {
+		return caller
+	}
+
+	rs := []rune(caller)
+	return "…" + string(rs[len(rs)-max+1:])
+}
+
+// split the text to lines, the long lines are wrapped on spaces to the width.
+// the tabs are replaced to 4 spaces. the width less than 20 will not wrap.
+func wrapLines(s string, width int) []string {
+	s = strings.Replace(strings.TrimRight(s, "\n"), "\t", "    ", -1)
+	if width < 20 {
+		return strings.Split(s, "\n")
+	}
+
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		for utf8.RuneCountInString(line) > width {
+			rs := []rune(line)
+			cut := width
+			// break on the last space
+			for i := width; i > width/2; i-- {
+				if rs[i] == ' ' {
+					cut = i
+					break
+				}
+			}
+
+			lines = append(lines, strings.TrimRight(string(rs[:cut]), " "))
+			line = strings.TrimLeft(string(rs[cut:]), " ")
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func padRight(s string, width int) string {
+	if n := width - utf8.RuneCountInString(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
@@ -0,0 +1,179 @@
+package slog
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ECSFormatter definition. format the record to an Elastic Common Schema(ECS) document.
+//
+// - @timestamp, log.level, message, log.logger(the channel), ecs.version
+// - log.origin.file.name, log.origin.file.line, log.origin.function from the Record.Caller
+// - error.message, error.type, error.stack_trace from the first error value in the Record.Fields
+// - the Data, Extra and Fields are nested under the namespaces, the dotted keys are expanded to objects.
+// eg: {"http.method": "GET"} will be {"http": {"method": "GET"}}
+//
+// NOTICE: only the PrettyPrint and TimeFormat of the JSONFormatter are used.
+type ECSFormatter struct {
+	JSONFormatter
+	// ECSVersion the ecs.version value. default is "8.11.0"
+	ECSVersion string
+	// DataNamespace the object name for the Record.Data, empty to put at the root. default is "data"
+	DataNamespace string
+	// ExtraNamespace the object name for the Record.Extra, empty to put at the root. default is "extra"
+	ExtraNamespace string
+	// FieldsNamespace the object name for the Record.Fields, empty to put at the root. default is empty
+	FieldsNamespace string
+}
+
+// NewECSFormatter create new ECSFormatter
+func NewECSFormatter(fn ...func(*ECSFormatter)) *ECSFormatter {
+	f := &ECSFormatter{
+		JSONFormatter: JSONFormatter{
+			Fields:     DefaultFields,
+			Aliases:    ECSAliases,
+			TimeFormat: time.RFC3339Nano,
+		},
+		ECSVersion:     "8.11.0",
+		DataNamespace:  "data",
+		ExtraNamespace: "extra",
+	}
+
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Configure current formatter
+func (f *ECSFormatter) Configure(fn func(*ECSFormatter)) *ECSFormatter {
+	fn(f)
+	return f
+}
+
+// Format an log record to ECS document
+func (f *ECSFormatter) Format(r *Record) ([]byte, error) {
+	return f.encode(r, f.Document(r))
+}
+
+// Document build the ECS document for the record
+func (f *ECSFormatter) Document(r *Record) M {
+	doc := make(M, 8)
+	setNamespace(doc, f.DataNamespace, r.Data)
+	setNamespace(doc, f.ExtraNamespace, r.Extra)
+
+	// the first error in the fields will be exported as error.*
+	var errKey string
+	fields := make(M, len(r.Fields))
+	for _, k := range sortedMapKeys(r.Fields) {
+		v := r.Fields[k]
+		if err, ok := v.(error); ok && err != nil {
+			if errKey == "" {
+				errKey = k
+				continue
+			}
+			v = err.Error()
+		}
+		fields[k] = v
+	}
+	setNamespace(doc, f.FieldsNamespace, fields)
+
+	if errKey != "" {
+		err := r.Fields[errKey].(error)
+		setDotted(doc, "error.message", err.Error())
+		setDotted(doc, "error.type", reflect.TypeOf(err).String())
+
+		// eg: the errors with stack from github.com/pkg/errors
+		if st := fmt.Sprintf("%+v", err); st != err.Error() {
+			setDotted(doc, "error.stack_trace", st)
+		}
+	}
+
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	setDotted(doc, "@timestamp", r.Time.Format(f.TimeFormat))
+	setDotted(doc, "log.level", r.LevelName())
+	setDotted(doc, "message", r.Message)
+	if r.Channel != "" {
+		setDotted(doc, "log.logger", r.Channel)
+	}
+	if f.ECSVersion != "" {
+		setDotted(doc, "ecs.version", f.ECSVersion)
+	}
+
+	if r.Caller != nil {
+		setDotted(doc, "log.origin.file.name", filepath.Base(r.Caller.File))
+		setDotted(doc, "log.origin.file.line", r.Caller.Line)
+		setDotted(doc, "log.origin.function", r.Caller.Function)
+	}
+	return doc
+}
+
+// set the map values into the namespace object, or the root on namespace is empty.
+func setNamespace(doc M, namespace string, mp map[string]interface{}) {
+	if len(mp) == 0 {
+		return
+	}
+
+	if namespace == "" {
+		for _, k := range sortedMapKeys(mp) {
+			setDotted(doc, k, mp[k])
+		}
+		return
+	}
+	setDotted(doc, namespace, mp)
+}
+
+// set the value by the dotted key, the objects will be created. eg: "log.origin.function"
+//
+// the map value will be copied, and its dotted keys are also expanded. if an key
+// on the path is an non-object value, the remaining key will be set as an flat key.
+func setDotted(mp M, key string, val interface{}) {
+	nodes := strings.Split(key, ".")
+	for i, node := range nodes[:len(nodes)-1] {
+		sub, ok := mp[node]
+		if !ok {
+			sm := make(M)
+			mp[node] = sm
+			mp = sm
+			continue
+		}
+
+		if sm, ok := sub.(M); ok {
+			mp = sm
+			continue
+		}
+
+		// conflict with an non-object value
+		mp[strings.Join(nodes[i:], ".")] = val
+		return
+	}
+
+	last := nodes[len(nodes)-1]
+	var sub map[string]interface{}
+	switch tv := val.(type) {
+	case map[string]interface{}:
+		sub = tv
+	case M:
+		sub = tv
+	default:
+		mp[last] = val
+		return
+	}
+
+	sm, ok := mp[last].(M)
+	if !ok {
+		sm = make(M, len(sub))
+		mp[last] = sm
+	}
+
+	for _, k := range sortedMapKeys(sub) {
+		setDotted(sm, k, sub[k])
+	}
+}
@@ -0,0 +1,205 @@
+package slog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// the special fields of the Google Cloud Logging structured logs
+const (
+	GCPKeySourceLocation = "logging.googleapis.com/sourceLocation"
+	GCPKeyTrace          = "logging.googleapis.com/trace"
+	GCPKeySpanID         = "logging.googleapis.com/spanId"
+	GCPKeyTraceSampled   = "logging.googleapis.com/trace_sampled"
+	GCPKeyLabels         = "logging.googleapis.com/labels"
+	// GCPKeyHTTPRequest the key of Record.Fields for the HTTP request info. the value can be an *GCPHTTPRequest or map
+	GCPKeyHTTPRequest = "httpRequest"
+)
+
+// the type for the Error Reporting
+const gcpErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// GCPHTTPRequest the HttpRequest of the Cloud Logging LogEntry
+type GCPHTTPRequest struct {
+	RequestMethod string `json:"requestMethod,omitempty"`
+	RequestURL    string `json:"requestUrl,omitempty"`
+	RequestSize   string `json:"requestSize,omitempty"`
+	Status        int    `json:"status,omitempty"`
+	ResponseSize  string `json:"responseSize,omitempty"`
+	UserAgent     string `json:"userAgent,omitempty"`
+	RemoteIP      string `json:"remoteIp,omitempty"`
+	ServerIP      string `json:"serverIp,omitempty"`
+	Referer       string `json:"referer,omitempty"`
+	// Latency eg: "0.125s"
+	Latency  string `json:"latency,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// GCPFormatter definition. format the record to the JSON line for Google Cloud Logging(GKE, Cloud Run).
+//
+// - severity is mapped from the level. see Level.GCPSeverity()
+// - sourceLocation is from the Record.Caller, trace and spanId are from the Record.Ctx. see Record.TraceContext()
+// - the Record.Fields[GCPKeyHTTPRequest] is exported as the httpRequest
+// - the error records(level <= ErrorLevel) with an error in the Fields are reported to the Error Reporting,
+// the stack trace of the error(by "%+v") is appended to the message, the error without stack is reported
+// by the Record.Caller as the context.reportLocation
+//
+// NOTICE: only the PrettyPrint of the JSONFormatter is used.
+type GCPFormatter struct {
+	JSONFormatter
+	// ProjectID for the trace name: "projects/{ProjectID}/traces/{TraceID}".
+	// default is from the env GOOGLE_CLOUD_PROJECT
+	ProjectID string
+	// Labels add to all entries
+	Labels map[string]string
+	// LabelFields the keys of the Record.Fields will be exported as labels
+	LabelFields []string
+	// ChannelLabel the label name for the record channel, empty to disable. default is "channel"
+	ChannelLabel string
+	// ServiceName and ServiceVersion for the Error Reporting serviceContext. optional
+	ServiceName    string
+	ServiceVersion string
+	// ReportErrors report the error records to the Error Reporting. default is true
+	ReportErrors bool
+}
+
+// NewGCPFormatter create new GCPFormatter
+func NewGCPFormatter(fn ...func(*GCPFormatter)) *GCPFormatter {
+	f := &GCPFormatter{
+		ProjectID:    os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		ChannelLabel: "channel",
+		ReportErrors: true,
+	}
+
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Configure current formatter
+func (f *GCPFormatter) Configure(fn func(*GCPFormatter)) *GCPFormatter {
+	fn(f)
+	return f
+}
+
+// Format an log record to the Cloud Logging JSON line
+func (f *GCPFormatter) Format(r *Record) ([]byte, error) {
+	return f.encode(r, f.Entry(r))
+}
+
+// Entry build the structured log entry for the record
+func (f *GCPFormatter) Entry(r *Record) M {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	entry := M{
+		"severity": r.Level.GCPSeverity(),
+		"time":     r.Time.Format(time.RFC3339Nano),
+	}
+
+	if len(r.Data) > 0 {
+		entry[FieldKeyData] = r.Data
+	}
+	if len(r.Extra) > 0 {
+		entry[FieldKeyExtra] = r.Extra
+	}
+
+	labels := make(map[string]string, len(f.Labels)+len(f.LabelFields)+1)
+	for k, v := range f.Labels {
+		labels[k] = v
+	}
+	if f.ChannelLabel != "" && r.Channel != "" {
+		labels[f.ChannelLabel] = r.Channel
+	}
+
+	var err error
+	for _, k := range sortedMapKeys(r.Fields) {
+		v := r.Fields[k]
+		if inArray(k, f.LabelFields) {
+			labels[k] = EncodeToString(v)
+			continue
+		}
+
+		if e, ok := v.(error); ok && e != nil {
+			if err == nil {
+				err = e
+			}
+			v = e.Error()
+		}
+
+		switch k {
+		case "severity", "time", "message", FieldKeyData, FieldKeyExtra:
+			k = "fields." + k
+		}
+		entry[k] = v
+	}
+
+	if len(labels) > 0 {
+		entry[GCPKeyLabels] = labels
+	}
+
+	if r.Caller != nil {
+		entry[GCPKeySourceLocation] = M{
+			"file":     r.Caller.File,
+			"line":     strconv.Itoa(r.Caller.Line),
+			"function": r.Caller.Function,
+		}
+	}
+
+	if tc, ok := r.TraceContext(); ok {
+		if f.ProjectID != "" {
+			entry[GCPKeyTrace] = "projects/" + f.ProjectID + "/traces/" + tc.TraceID
+		} else {
+			entry[GCPKeyTrace] = tc.TraceID
+		}
+		entry[GCPKeySpanID] = tc.SpanID
+		entry[GCPKeyTraceSampled] = tc.Sampled
+	}
+
+	entry["message"] = r.Message
+	if f.ReportErrors && err != nil && r.Level <= ErrorLevel {
+		f.reportError(r, entry, err)
+	}
+	return entry
+}
+
+// mark the entry as an ReportedErrorEvent, and append the stack trace of the error to the message.
+// the error without stack is reported by the caller as the context.reportLocation.
+func (f *GCPFormatter) reportError(r *Record, entry M, err error) {
+	entry["@type"] = gcpErrorEventType
+	if f.ServiceName != "" {
+		entry["serviceContext"] = M{"service": f.ServiceName, "version": f.ServiceVersion}
+	}
+
+	// the errors with stack. eg: from github.com/pkg/errors
+	detail := fmt.Sprintf("%+v", err)
+	if detail == err.Error() && r.Caller != nil {
+		entry["context"] = M{
+			"reportLocation": M{
+				"filePath":     r.Caller.File,
+				"lineNumber":   r.Caller.Line,
+				"functionName": r.Caller.Function,
+			},
+		}
+	}
+
+	msg := detail
+	if r.Message != "" {
+		msg = r.Message + ": " + detail
+	}
+	entry["message"] = msg
+}
+
+func inArray(s string, list []string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
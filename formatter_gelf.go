@@ -0,0 +1,140 @@
+package slog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// GELFVersion the GELF spec version
+const GELFVersion = "1.1"
+
+// GELFFormatter definition. format the record to an GELF 1.1 message for Graylog.
+//
+// - short_message is the first line of the Message, full_message is the Message if it is multi-line
+// - level is the syslog severity of the record level. see Level.SyslogSeverity()
+// - timestamp is the UNIX seconds with the fractional part
+// - Extra, Data, Fields are exported as the additional fields with "_" prefix.
+// nested maps are flattened to dotted keys. eg: "_user.id"
+//
+// NOTICE: the formatted message not contains an trailing newline.
+type GELFFormatter struct {
+	// Host the name of the host sending the message. default is os.Hostname()
+	Host string
+	// ChannelKey the additional field name for the record channel, empty to disable. default is "channel"
+	ChannelKey string
+	// CallerKey the additional field name for the record caller, empty to disable. default is "caller"
+	CallerKey string
+}
+
+// NewGELFFormatter create new GELFFormatter
+func NewGELFFormatter(fn ...func(*GELFFormatter)) *GELFFormatter {
+	host, _ := os.Hostname()
+	f := &GELFFormatter{
+		Host:       host,
+		ChannelKey: FieldKeyChannel,
+		CallerKey:  FieldKeyCaller,
+	}
+
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Configure current formatter
+func (f *GELFFormatter) Configure(fn func(*GELFFormatter)) *GELFFormatter {
+	fn(f)
+	return f
+}
+
+// Format an log record
+func (f *GELFFormatter) Format(r *Record) ([]byte, error) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	msg := M{
+		"version":   GELFVersion,
+		"host":      f.Host,
+		"timestamp": float64(r.Time.UnixNano()/int64(time.Millisecond)) / 1000,
+		"level":     r.Level.SyslogSeverity(),
+	}
+
+	short := strings.TrimSpace(r.Message)
+	if pos := strings.IndexByte(short, '\n'); pos > 0 {
+		msg["short_message"] = strings.TrimSpace(short[:pos])
+		msg["full_message"] = r.Message
+	} else {
+		msg["short_message"] = short
+	}
+
+	// short_message is required
+	if msg["short_message"] == "" {
+		msg["short_message"] = "-"
+	}
+
+	// the later will override the former
+	f.addFields(msg, "", r.Extra)
+	f.addFields(msg, "", r.Data)
+	f.addFields(msg, "", r.Fields)
+
+	if f.ChannelKey != "" && r.Channel != "" {
+		msg[gelfFieldName(f.ChannelKey)] = r.Channel
+	}
+	if f.CallerKey != "" && r.Caller != nil {
+		msg[gelfFieldName(f.CallerKey)] = formatCaller(r.Caller, FieldKeyFile)
+	}
+
+	return json.Marshal(msg)
+}
+
+func (f *GELFFormatter) addFields(msg M, prefix string, mp map[string]interface{}) {
+	for key, val := range mp {
+		key = prefix + key
+		switch tv := val.(type) {
+		case M:
+			f.addFields(msg, key+".", tv)
+		case map[string]interface{}:
+			f.addFields(msg, key+".", tv)
+		default:
+			msg[gelfFieldName(key)] = gelfValue(val)
+		}
+	}
+}
+
+// the additional field name must match ^[\w\.\-]*$, and "_id" is reserved.
+func gelfFieldName(key string) string {
+	name := []byte("_" + key)
+	for i, c := range name {
+		if !(c == '_' || c == '.' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			name[i] = '_'
+		}
+	}
+
+	if string(name) == "_id" {
+		return "_id_"
+	}
+	return string(name)
+}
+
+// the additional field value must be an string or number
+func gelfValue(val interface{}) interface{} {
+	switch tv := val.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return tv
+	case string:
+		return tv
+	case error:
+		return tv.Error()
+	case fmt.Stringer:
+		return tv.String()
+	case nil:
+		return ""
+	}
+
+	return fmt.Sprint(val)
+}
@@ -2,10 +2,36 @@ package slog
 
 import (
 	"encoding/json"
+	"strconv"
 	"time"
 )
 
+// ECSAliases the Elastic Common Schema(ECS) style field aliases for the JSONFormatter.
+//
+// Usage:
+// 	f := slog.NewJSONFormatter(func(f *slog.JSONFormatter) {
+// 		f.Aliases = slog.ECSAliases
+// 		f.TimeFormat = time.RFC3339Nano
+// 	})
+var ECSAliases = StringMap{
+	FieldKeyDatetime: "@timestamp",
+	FieldKeyLevel:    "log.level",
+	FieldKeyChannel:  "log.logger",
+	FieldKeyFunc:     "log.origin.function",
+	FieldKeyFile:     "log.origin.file.name",
+	FieldKeyMessage:  "message",
+}
+
 // JSONFormatter definition
+//
+// The keys are output by the Fields order, then the custom fields(Record.Fields)
+// by the insertion order or sorted order. see SortFields
+//
+// The record is encoded by an append-style encoder, the output is same as the encoding/json, except:
+//
+// - the error and fmt.Stringer values are encoded as string. eg: {"error": "not found"}
+// - the NaN and Inf floats are encoded as string
+// - the HTML chars <, >, & are escaped only on EscapeHTML is true
 type JSONFormatter struct {
 	// Fields exported log fields.
 	Fields []string
@@ -18,6 +44,10 @@ type JSONFormatter struct {
 	PrettyPrint bool
 	// TimeFormat the time format layout. default is time.RFC3339
 	TimeFormat string
+	// SortFields output the custom fields sorted by key. default is by the insertion order
+	SortFields bool
+	// EscapeHTML escape the HTML chars <, >, & in the strings. default is false
+	EscapeHTML bool
 }
 
 // NewJSONFormatter create new JSONFormatter
@@ -43,7 +73,22 @@ func (f *JSONFormatter) Configure(fn func(*JSONFormatter)) *JSONFormatter {
 
 // Format an log record
 func (f *JSONFormatter) Format(r *Record) ([]byte, error) {
-	logData := make(M, len(f.Fields))
+	bp := jsonBufferPool.Get().(*[]byte)
+	buf := f.appendRecord(append((*bp)[:0], '{'), r)
+	out, err := f.output(r, append(buf, '}'))
+
+	if cap(buf) <= maxPooledJSONBuffer {
+		*bp = buf
+		jsonBufferPool.Put(bp)
+	}
+	return out, err
+}
+
+func (f *JSONFormatter) appendRecord(buf []byte, r *Record) []byte {
+	e := jsonEncoder{escapeHTML: f.EscapeHTML}
+	// the exported field names
+	var names [16]string
+	exported := names[:0]
 
 	for _, field := range f.Fields {
 		outName, ok := f.Aliases[field]
@@ -51,58 +96,112 @@ func (f *JSONFormatter) Format(r *Record) ([]byte, error) {
 			outName = field
 		}
 
+		if inArray(outName, exported) {
+			continue
+		}
+
+		size := len(buf)
 		switch {
 		case field == FieldKeyDatetime:
 			if r.Time.IsZero() {
 				r.Time = time.Now()
 			}
 
-			logData[outName] = r.Time.Format(f.TimeFormat)
+			buf = append(f.appendKey(e, buf, outName), '"')
+			start := len(buf)
+			buf = r.Time.AppendFormat(buf, f.TimeFormat)
+			if !e.isSafeString(buf[start:]) {
+				buf = e.appendString(buf[:start-1], string(buf[start:]))
+			} else {
+				buf = append(buf, '"')
+			}
 		case field == FieldKeyTimestamp:
-			logData[outName] = r.MicroSecond()
+			buf = strconv.AppendInt(f.appendKey(e, buf, outName), int64(r.MicroSecond()), 10)
 		case field == FieldKeyCaller && r.Caller != nil:
-			logData[outName] = formatCaller(r.Caller, field) // "logger_test.go:48,TestLogger_ReportCaller"
+			buf = e.appendString(f.appendKey(e, buf, outName), formatCaller(r.Caller, field)) // "logger_test.go:48,TestLogger_ReportCaller"
 		case field == FieldKeyFLine && r.Caller != nil:
-			logData[outName] = formatCaller(r.Caller, field) // "logger_test.go:48"
+			buf = e.appendString(f.appendKey(e, buf, outName), formatCaller(r.Caller, field)) // "logger_test.go:48"
 		case field == FieldKeyFunc && r.Caller != nil:
-			logData[outName] = r.Caller.Function // "github.com/gookit/slog_test.TestLogger_ReportCaller"
+			buf = e.appendString(f.appendKey(e, buf, outName), r.Caller.Function) // "github.com/gookit/slog_test.TestLogger_ReportCaller"
 		case field == FieldKeyFile && r.Caller != nil:
-			logData[outName] = formatCaller(r.Caller, field) // "/work/go/gookit/slog/logger_test.go:48"
+			buf = e.appendString(f.appendKey(e, buf, outName), formatCaller(r.Caller, field)) // "/work/go/gookit/slog/logger_test.go:48"
 		case field == FieldKeyLevel:
-			logData[outName] = r.LevelName()
+			buf = e.appendString(f.appendKey(e, buf, outName), r.LevelName())
 		case field == FieldKeyChannel:
-			logData[outName] = r.Channel
+			buf = e.appendString(f.appendKey(e, buf, outName), r.Channel)
 		case field == FieldKeyMessage:
-			logData[outName] = r.Message
+			buf = e.appendString(f.appendKey(e, buf, outName), r.Message)
 		case field == FieldKeyData:
-			logData[outName] = r.Data
+			buf = e.appendMap(f.appendKey(e, buf, outName), r.Data)
 		case field == FieldKeyExtra:
-			logData[outName] = r.Extra
-			// default:
-			// 	logData[outName] = r.Fields[field]
+			buf = e.appendMap(f.appendKey(e, buf, outName), r.Extra)
+		}
+
+		if len(buf) > size {
+			exported = append(exported, outName)
 		}
 	}
 
 	// exported custom fields
-	for field, value := range r.Fields {
+	var fieldKeys []string
+	if f.SortFields {
+		fieldKeys = sortedMapKeys(r.Fields)
+	} else {
+		fieldKeys = r.FieldKeys()
+	}
+
+	for _, field := range fieldKeys {
 		fieldKey := field
-		if _, has := logData[field]; has {
+		if inArray(field, exported) {
 			fieldKey = "fields." + field
 		}
 
-		logData[fieldKey] = value
+		buf = e.appendValue(f.appendKey(e, buf, fieldKey), r.Fields[field])
 	}
+	return buf
+}
 
-	// sort.Interface()
+// append the key and ':'. the buf is started with '{'
+func (f *JSONFormatter) appendKey(e jsonEncoder, buf []byte, key string) []byte {
+	if len(buf) > 1 {
+		buf = append(buf, ',')
+	}
+	return append(e.appendString(buf, key), ':')
+}
 
-	buffer := r.NewBuffer()
-	encoder := json.NewEncoder(buffer)
+// encode the log data to JSON, by the record buffer
+func (f *JSONFormatter) encode(r *Record, logData M) ([]byte, error) {
+	bp := jsonBufferPool.Get().(*[]byte)
+	buf := jsonEncoder{escapeHTML: f.EscapeHTML}.appendMap((*bp)[:0], logData)
+	out, err := f.output(r, buf)
 
+	if cap(buf) <= maxPooledJSONBuffer {
+		*bp = buf
+		jsonBufferPool.Put(bp)
+	}
+	return out, err
+}
+
+// write the JSON with newline to the record buffer, or an new slice. the buf is from the pool
+func (f *JSONFormatter) output(r *Record, buf []byte) ([]byte, error) {
 	if f.PrettyPrint {
-		encoder.SetIndent("", "  ")
+		buffer := r.NewBuffer()
+		if err := json.Indent(buffer, buf, "", "  "); err != nil {
+			return nil, err
+		}
+
+		buffer.WriteByte('\n')
+		return buffer.Bytes(), nil
+	}
+
+	if r.Buffer != nil {
+		r.Buffer.Write(buf)
+		r.Buffer.WriteByte('\n')
+		return r.Buffer.Bytes(), nil
 	}
 
-	// has been add newline in Encode().
-	err := encoder.Encode(logData)
-	return buffer.Bytes(), err
+	out := make([]byte, len(buf)+1)
+	copy(out, buf)
+	out[len(buf)] = '\n'
+	return out, nil
 }
@@ -0,0 +1,247 @@
+package slog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// LogfmtFormatter definition. format the record to an logfmt line.
+//
+// eg:
+// 	datetime=2021-03-01T12:00:00+08:00 channel=application level=INFO message="user login" data.uid=23
+//
+// - the values contains space, '=', '"' or control chars will be quoted and escaped
+// - the nested maps in Data, Extra, Fields will be flattened to dotted keys. eg: "data.user.id=23"
+// - the keys are output by the Fields order, then the sorted custom fields
+type LogfmtFormatter struct {
+	// Fields exported log fields.
+	Fields []string
+	// Aliases for output fields. you can change export field name.
+	// item: `"field" : "output name"`
+	// eg: {"message": "msg"} export field will display "msg"
+	Aliases StringMap
+
+	// TimeFormat the time format layout. default is time.RFC3339
+	TimeFormat string
+}
+
+// NewLogfmtFormatter create new LogfmtFormatter
+func NewLogfmtFormatter(fn ...func(*LogfmtFormatter)) *LogfmtFormatter {
+	f := &LogfmtFormatter{
+		Fields:     DefaultFields,
+		TimeFormat: time.RFC3339,
+	}
+
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Configure current formatter
+func (f *LogfmtFormatter) Configure(fn func(*LogfmtFormatter)) *LogfmtFormatter {
+	fn(f)
+	return f
+}
+
+// Format an log record
+func (f *LogfmtFormatter) Format(r *Record) ([]byte, error) {
+	buf := make([]byte, 0, 256)
+	exported := make(map[string]bool, len(f.Fields))
+	// the custom fields has been exported by Fields
+	done := make(map[string]bool)
+
+	for _, field := range f.Fields {
+		outName, ok := f.Aliases[field]
+		if !ok {
+			outName = field
+		}
+
+		switch {
+		case field == FieldKeyDatetime:
+			if r.Time.IsZero() {
+				r.Time = time.Now()
+			}
+
+			buf = f.appendPair(buf, outName, r.Time.Format(f.TimeFormat))
+		case field == FieldKeyTimestamp:
+			buf = f.appendPair(buf, outName, r.MicroSecond())
+		case field == FieldKeyCaller && r.Caller != nil:
+			buf = f.appendPair(buf, outName, formatCaller(r.Caller, field))
+		case field == FieldKeyFLine && r.Caller != nil:
+			buf = f.appendPair(buf, outName, formatCaller(r.Caller, field))
+		case field == FieldKeyFunc && r.Caller != nil:
+			buf = f.appendPair(buf, outName, r.Caller.Function)
+		case field == FieldKeyFile && r.Caller != nil:
+			buf = f.appendPair(buf, outName, formatCaller(r.Caller, field))
+		case field == FieldKeyLevel:
+			buf = f.appendPair(buf, outName, r.LevelName())
+		case field == FieldKeyChannel:
+			buf = f.appendPair(buf, outName, r.Channel)
+		case field == FieldKeyMessage:
+			buf = f.appendPair(buf, outName, r.Message)
+		case field == FieldKeyData:
+			buf = f.appendPair(buf, outName, r.Data)
+		case field == FieldKeyExtra:
+			buf = f.appendPair(buf, outName, r.Extra)
+		default:
+			if val, ok := r.Fields[field]; ok {
+				buf = f.appendPair(buf, outName, val)
+				done[field] = true
+			}
+		}
+
+		exported[outName] = true
+	}
+
+	// exported custom fields, sorted by key
+	for _, field := range sortedMapKeys(r.Fields) {
+		if done[field] {
+			continue
+		}
+
+		key := field
+		if exported[field] {
+			key = "fields." + field
+		}
+		buf = f.appendPair(buf, key, r.Fields[field])
+	}
+
+	return append(buf, '\n'), nil
+}
+
+func (f *LogfmtFormatter) appendPair(buf []byte, key string, val interface{}) []byte {
+	switch tv := val.(type) {
+	case M:
+		return f.appendMap(buf, key, tv)
+	case map[string]interface{}:
+		return f.appendMap(buf, key, tv)
+	case map[string]string:
+		mp := make(M, len(tv))
+		for k, v := range tv {
+			mp[k] = v
+		}
+		return f.appendMap(buf, key, mp)
+	}
+
+	if len(buf) > 0 {
+		buf = append(buf, ' ')
+	}
+
+	buf = appendLogfmtKey(buf, key)
+	buf = append(buf, '=')
+	return appendLogfmtValue(buf, f.valueString(val))
+}
+
+// flatten the nested map to dotted keys
+func (f *LogfmtFormatter) appendMap(buf []byte, prefix string, mp map[string]interface{}) []byte {
+	for _, k := range sortedMapKeys(mp) {
+		if prefix != "" {
+			buf = f.appendPair(buf, prefix+"."+k, mp[k])
+		} else {
+			buf = f.appendPair(buf, k, mp[k])
+		}
+	}
+	return buf
+}
+
+func (f *LogfmtFormatter) valueString(val interface{}) string {
+	switch tv := val.(type) {
+	case nil:
+		return "null"
+	case string:
+		return tv
+	case []byte:
+		return string(tv)
+	case error:
+		return tv.Error()
+	case time.Time:
+		return tv.Format(f.TimeFormat)
+	case fmt.Stringer:
+		return tv.String()
+	case bool:
+		return strconv.FormatBool(tv)
+	case int:
+		return strconv.Itoa(tv)
+	case int64:
+		return strconv.FormatInt(tv, 10)
+	case uint64:
+		return strconv.FormatUint(tv, 10)
+	case float32:
+		return strconv.FormatFloat(float64(tv), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(tv, 'g', -1, 64)
+	}
+
+	return fmt.Sprint(val)
+}
+
+// the invalid chars in key will be replaced to '_'
+func appendLogfmtKey(buf []byte, key string) []byte {
+	if key == "" {
+		return append(buf, '_')
+	}
+
+	for _, c := range key {
+		if c <= ' ' || c == '=' || c == '"' || c == utf8.RuneError {
+			buf = append(buf, '_')
+		} else {
+			buf = append(buf, string(c)...)
+		}
+	}
+	return buf
+}
+
+func appendLogfmtValue(buf []byte, val string) []byte {
+	if !needsQuote(val) {
+		return append(buf, val...)
+	}
+
+	buf = append(buf, '"')
+	for _, c := range val {
+		switch c {
+		case '"', '\\':
+			buf = append(buf, '\\', byte(c))
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			if c < ' ' || c == utf8.RuneError {
+				buf = append(buf, fmt.Sprintf(`\u%04x`, c)...)
+			} else {
+				buf = append(buf, string(c)...)
+			}
+		}
+	}
+	return append(buf, '"')
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+
+	for _, c := range s {
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == utf8.RuneError {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedMapKeys(mp map[string]interface{}) []string {
+	keys := make([]string, 0, len(mp))
+	for k := range mp {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
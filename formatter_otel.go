@@ -0,0 +1,227 @@
+package slog
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OTelAnyValue the AnyValue of the OpenTelemetry data model, in the OTLP/JSON encoding.
+// only one of the fields is set.
+type OTelAnyValue struct {
+	StringValue *string          `json:"stringValue,omitempty"`
+	BoolValue   *bool            `json:"boolValue,omitempty"`
+	IntValue    *string          `json:"intValue,omitempty"`
+	DoubleValue *float64         `json:"doubleValue,omitempty"`
+	BytesValue  *string          `json:"bytesValue,omitempty"`
+	ArrayValue  *OTelArrayValue  `json:"arrayValue,omitempty"`
+	KvlistValue *OTelKvlistValue `json:"kvlistValue,omitempty"`
+}
+
+// OTelArrayValue an array of AnyValue
+type OTelArrayValue struct {
+	Values []OTelAnyValue `json:"values"`
+}
+
+// OTelKvlistValue an list of KeyValue
+type OTelKvlistValue struct {
+	Values []OTelKeyValue `json:"values"`
+}
+
+// OTelKeyValue an attribute
+type OTelKeyValue struct {
+	Key   string       `json:"key"`
+	Value OTelAnyValue `json:"value"`
+}
+
+// OTelLogRecord the LogRecord of the OpenTelemetry data model, in the OTLP/JSON encoding.
+type OTelLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 OTelAnyValue   `json:"body"`
+	Attributes           []OTelKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+	Flags                uint32         `json:"flags,omitempty"`
+}
+
+// OTelFormatter definition. map the record to the OpenTelemetry LogRecord data model.
+//
+// - SeverityNumber is mapped from the level. see Level.OTelSeverity()
+// - Body is the Message
+// - Attributes are from the Extra, Data, Fields(the later will override the former),
+// the channel and the caller(code.* semantic conventions)
+// - TraceID, SpanID are from the Record.Ctx. see Record.TraceContext()
+//
+// The Format() output an LogRecord in the OTLP/JSON encoding.
+type OTelFormatter struct {
+	// Resource attributes. default has "service.name" and "host.name"
+	Resource M
+	// ScopeName the instrumentation scope name. default is "github.com/tomorrowsky/slog"
+	ScopeName string
+	// ChannelKey the attribute name for the record channel, empty to disable. default is "log.channel"
+	ChannelKey string
+}
+
+// NewOTelFormatter create new OTelFormatter
+func NewOTelFormatter(fn ...func(*OTelFormatter)) *OTelFormatter {
+	host, _ := os.Hostname()
+	f := &OTelFormatter{
+		Resource: M{
+			"service.name": filepath.Base(os.Args[0]),
+			"host.name":    host,
+		},
+		ScopeName:  "github.com/tomorrowsky/slog",
+		ChannelKey: "log.channel",
+	}
+
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Configure current formatter
+func (f *OTelFormatter) Configure(fn func(*OTelFormatter)) *OTelFormatter {
+	fn(f)
+	return f
+}
+
+// Format an log record to the OTLP/JSON LogRecord
+func (f *OTelFormatter) Format(r *Record) ([]byte, error) {
+	return json.Marshal(f.LogRecord(r))
+}
+
+// LogRecord map the record to the OTel LogRecord
+func (f *OTelFormatter) LogRecord(r *Record) *OTelLogRecord {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	lr := &OTelLogRecord{
+		TimeUnixNano:         strconv.FormatInt(r.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       r.Level.OTelSeverity(),
+		SeverityText:         r.LevelName(),
+		Body:                 OTelValue(r.Message),
+	}
+
+	attrs := make(M, len(r.Data)+len(r.Extra)+len(r.Fields)+4)
+	for k, v := range r.Extra {
+		attrs[k] = v
+	}
+	for k, v := range r.Data {
+		attrs[k] = v
+	}
+	for k, v := range r.Fields {
+		attrs[k] = v
+	}
+
+	if f.ChannelKey != "" && r.Channel != "" {
+		attrs[f.ChannelKey] = r.Channel
+	}
+	if r.Caller != nil {
+		attrs["code.filepath"] = r.Caller.File
+		attrs["code.lineno"] = r.Caller.Line
+		attrs["code.function"] = r.Caller.Function
+	}
+	lr.Attributes = OTelAttributes(attrs)
+
+	if tc, ok := r.TraceContext(); ok {
+		lr.TraceID = tc.TraceID
+		lr.SpanID = tc.SpanID
+		if tc.Sampled {
+			lr.Flags = 1
+		}
+	}
+	return lr
+}
+
+// ResourceAttributes get the resource attributes
+func (f *OTelFormatter) ResourceAttributes() []OTelKeyValue {
+	return OTelAttributes(f.Resource)
+}
+
+// OTelAttributes convert the map to the attributes, sorted by key.
+func OTelAttributes(mp map[string]interface{}) []OTelKeyValue {
+	keys := make([]string, 0, len(mp))
+	for k := range mp {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]OTelKeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, OTelKeyValue{Key: k, Value: OTelValue(mp[k])})
+	}
+	return kvs
+}
+
+// OTelValue convert the value to the AnyValue
+func OTelValue(v interface{}) OTelAnyValue {
+	switch tv := v.(type) {
+	case nil:
+		return OTelAnyValue{}
+	case string:
+		return OTelAnyValue{StringValue: &tv}
+	case bool:
+		return OTelAnyValue{BoolValue: &tv}
+	case int, int8, int16, int32, int64:
+		s := strconv.FormatInt(reflect.ValueOf(tv).Int(), 10)
+		return OTelAnyValue{IntValue: &s}
+	case uint, uint8, uint16, uint32, uint64:
+		s := strconv.FormatUint(reflect.ValueOf(tv).Uint(), 10)
+		return OTelAnyValue{IntValue: &s}
+	case float32:
+		f := float64(tv)
+		return OTelAnyValue{DoubleValue: &f}
+	case float64:
+		return OTelAnyValue{DoubleValue: &tv}
+	case []byte:
+		s := base64.StdEncoding.EncodeToString(tv)
+		return OTelAnyValue{BytesValue: &s}
+	case time.Time:
+		s := tv.Format(time.RFC3339Nano)
+		return OTelAnyValue{StringValue: &s}
+	case error:
+		s := tv.Error()
+		return OTelAnyValue{StringValue: &s}
+	case map[string]interface{}:
+		return OTelAnyValue{KvlistValue: &OTelKvlistValue{Values: OTelAttributes(tv)}}
+	case M:
+		return OTelAnyValue{KvlistValue: &OTelKvlistValue{Values: OTelAttributes(tv)}}
+	case fmt.Stringer:
+		s := tv.String()
+		return OTelAnyValue{StringValue: &s}
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		arr := &OTelArrayValue{Values: make([]OTelAnyValue, rv.Len())}
+		for i := range arr.Values {
+			arr.Values[i] = OTelValue(rv.Index(i).Interface())
+		}
+		return OTelAnyValue{ArrayValue: arr}
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			mp := make(map[string]interface{}, rv.Len())
+			for _, key := range rv.MapKeys() {
+				mp[key.String()] = rv.MapIndex(key).Interface()
+			}
+			return OTelAnyValue{KvlistValue: &OTelKvlistValue{Values: OTelAttributes(mp)}}
+		}
+	}
+
+	s := fmt.Sprint(v)
+	return OTelAnyValue{StringValue: &s}
+}
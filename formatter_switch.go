@@ -0,0 +1,287 @@
+package slog
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+)
+
+// SwitchRule an rule of the SwitchFormatter. the empty conditions match any record,
+// the record must match all the set conditions.
+type SwitchRule struct {
+	// Levels match the record level
+	Levels Levels
+	// Channels match the record channel
+	Channels []string
+	// Match the custom condition
+	Match func(r *Record) bool
+	// Formatter format the matched records. the rule is skipped on it is nil
+	Formatter Formatter
+}
+
+// IsMatch check the record is matched
+func (rule *SwitchRule) IsMatch(r *Record) bool {
+	if len(rule.Levels) > 0 && !rule.Levels.Contains(r.Level) {
+		return false
+	}
+
+	if len(rule.Channels) > 0 {
+		var found bool
+		for _, ch := range rule.Channels {
+			if ch == r.Channel {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return rule.Match == nil || rule.Match(r)
+}
+
+// SwitchFormatter definition. format the record by the formatter of the first matched rule,
+// so an handler can format the records by level, channel or any condition.
+//
+// eg: the error records with the caller and error stack, the others are compact
+//
+// Usage:
+// 	f := slog.NewSwitchFormatter(slog.NewTextFormatter("[{{level}}] {{message}}\n"))
+// 	f.OnLevels(slog.NewDevFormatter(), slog.DangerLevels...)
+// 	h.SetFormatter(f)
+//
+// It can also load from the config file, see LoadSwitchFormatter()
+type SwitchFormatter struct {
+	// Rules the rules are matched in order
+	Rules []*SwitchRule
+	// Default format the records that not match any rule. default is the TextFormatter
+	Default Formatter
+}
+
+// ErrNoSwitchFormatter returns on the record not match any rule and the Default is nil
+var ErrNoSwitchFormatter = errors.New("slog: the record not match any rule and the default formatter is nil")
+
+// NewSwitchFormatter create new SwitchFormatter. the default is the TextFormatter on the def is nil
+func NewSwitchFormatter(def Formatter, fn ...func(*SwitchFormatter)) *SwitchFormatter {
+	f := &SwitchFormatter{Default: def}
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	if f.Default == nil {
+		f.Default = NewTextFormatter()
+	}
+	return f
+}
+
+// Configure current formatter
+func (f *SwitchFormatter) Configure(fn func(*SwitchFormatter)) *SwitchFormatter {
+	fn(f)
+	return f
+}
+
+// AddRule add an rule to the end
+func (f *SwitchFormatter) AddRule(rule *SwitchRule) *SwitchFormatter {
+	f.Rules = append(f.Rules, rule)
+	return f
+}
+
+// OnLevels use the formatter for the levels
+func (f *SwitchFormatter) OnLevels(formatter Formatter, levels ...Level) *SwitchFormatter {
+	return f.AddRule(&SwitchRule{Levels: levels, Formatter: formatter})
+}
+
+// OnChannels use the formatter for the channels
+func (f *SwitchFormatter) OnChannels(formatter Formatter, channels ...string) *SwitchFormatter {
+	return f.AddRule(&SwitchRule{Channels: channels, Formatter: formatter})
+}
+
+// OnMatch use the formatter for the records matched by the fn
+func (f *SwitchFormatter) OnMatch(fn func(r *Record) bool, formatter Formatter) *SwitchFormatter {
+	return f.AddRule(&SwitchRule{Match: fn, Formatter: formatter})
+}
+
+// Select get the formatter for the record, the rules without formatter are skipped
+func (f *SwitchFormatter) Select(r *Record) Formatter {
+	for _, rule := range f.Rules {
+		if rule != nil && rule.Formatter != nil && rule.IsMatch(r) {
+			return rule.Formatter
+		}
+	}
+	return f.Default
+}
+
+// Format an log record by the selected formatter
+func (f *SwitchFormatter) Format(r *Record) ([]byte, error) {
+	fm := f.Select(r)
+	if fm == nil {
+		return nil, ErrNoSwitchFormatter
+	}
+	return fm.Format(r)
+}
+
+var (
+	// Formatters the registered formatters by name, for the SwitchFormatter config.
+	// the custom formatter can be registered to it.
+	//
+	// eg:
+	// 	slog.Formatters["mine"] = func() slog.Formatter {
+	// 		return slog.NewJSONFormatter(func(f *slog.JSONFormatter) {
+	// 			f.Aliases = slog.StringMap{"message": "msg"}
+	// 		})
+	// 	}
+	Formatters = map[string]func() Formatter{
+		"text":    func() Formatter { return NewTextFormatter() },
+		"json":    func() Formatter { return NewJSONFormatter() },
+		"logfmt":  func() Formatter { return NewLogfmtFormatter() },
+		"dev":     func() Formatter { return NewDevFormatter() },
+		"ecs":     func() Formatter { return NewECSFormatter() },
+		"gcp":     func() Formatter { return NewGCPFormatter() },
+		"gelf":    func() Formatter { return NewGELFFormatter() },
+		"otel":    func() Formatter { return NewOTelFormatter() },
+		"syslog":  func() Formatter { return NewSyslogFormatter() },
+		"cef":     func() Formatter { return NewCEFFormatter() },
+		"leef":    func() Formatter { return NewLEEFFormatter() },
+		"msgpack": func() Formatter { return NewMsgpackFormatter() },
+		"cbor":    func() Formatter { return NewCBORFormatter() },
+	}
+
+	// SwitchMatchers the registered conditions by name, for the "match" of the SwitchFormatter config.
+	SwitchMatchers = map[string]func(r *Record) bool{
+		// the record has an error in the Fields
+		"has_error": func(r *Record) bool {
+			for _, v := range r.Fields {
+				if _, ok := v.(error); ok {
+					return true
+				}
+			}
+			return false
+		},
+		"has_caller": func(r *Record) bool {
+			return r.Caller != nil
+		},
+	}
+)
+
+// SwitchFormatterConfig the config of the SwitchFormatter
+type SwitchFormatterConfig struct {
+	// Default the default formatter. default is "text"
+	Default SwitchFormatterRef `json:"default"`
+	Rules   []SwitchRuleConfig `json:"rules"`
+}
+
+// SwitchRuleConfig the rule config of the SwitchFormatter
+type SwitchRuleConfig struct {
+	// Levels the level names. eg: ["error", "fatal"]
+	Levels   []string `json:"levels"`
+	Channels []string `json:"channels"`
+	// Match the name of the SwitchMatchers
+	Match     string             `json:"match"`
+	Formatter SwitchFormatterRef `json:"formatter"`
+}
+
+// SwitchFormatterRef the formatter in the SwitchFormatterConfig.
+// the Name is from the Formatters, the Template only for the "text" formatter.
+//
+// eg: "json" or {"name": "text", "template": "[{{level}}] {{message}}\n"}
+type SwitchFormatterRef struct {
+	Name     string `json:"name"`
+	Template string `json:"template"`
+}
+
+// UnmarshalJSON from the name string or object
+func (ref *SwitchFormatterRef) UnmarshalJSON(bs []byte) error {
+	if len(bs) > 0 && bs[0] == '"' {
+		ref.Template = ""
+		return json.Unmarshal(bs, &ref.Name)
+	}
+
+	type plain SwitchFormatterRef
+	return json.Unmarshal(bs, (*plain)(ref))
+}
+
+// New create the formatter
+func (ref SwitchFormatterRef) New() (Formatter, error) {
+	name := ref.Name
+	if name == "" {
+		name = "text"
+	}
+
+	if ref.Template != "" {
+		if name != "text" {
+			return nil, errors.New("slog: the template only for the text formatter, but got: " + name)
+		}
+		return NewTextFormatter(ref.Template), nil
+	}
+
+	fn, ok := Formatters[name]
+	if !ok {
+		return nil, errors.New("slog: formatter not found: " + name)
+	}
+	return fn(), nil
+}
+
+// NewSwitchFormatterWithConfig create new SwitchFormatter by the config
+func NewSwitchFormatterWithConfig(cfg *SwitchFormatterConfig) (*SwitchFormatter, error) {
+	def, err := cfg.Default.New()
+	if err != nil {
+		return nil, err
+	}
+
+	f := NewSwitchFormatter(def)
+	for _, rc := range cfg.Rules {
+		rule := &SwitchRule{Channels: rc.Channels}
+		for _, name := range rc.Levels {
+			level, err := Name2Level(name)
+			if err != nil {
+				return nil, err
+			}
+			rule.Levels = append(rule.Levels, level)
+		}
+
+		if rc.Match != "" {
+			fn, ok := SwitchMatchers[rc.Match]
+			if !ok {
+				return nil, errors.New("slog: switch matcher not found: " + rc.Match)
+			}
+			rule.Match = fn
+		}
+
+		if rule.Formatter, err = rc.Formatter.New(); err != nil {
+			return nil, err
+		}
+		f.AddRule(rule)
+	}
+	return f, nil
+}
+
+// LoadSwitchFormatter load the SwitchFormatter from JSON.
+//
+// eg:
+// 	{
+// 		"default": {"name": "text", "template": "[{{level}}] {{message}}\n"},
+// 		"rules": [
+// 			{"levels": ["error", "fatal", "panic"], "formatter": "dev"},
+// 			{"channels": ["http"], "match": "has_error", "formatter": "json"}
+// 		]
+// 	}
+func LoadSwitchFormatter(r io.Reader) (*SwitchFormatter, error) {
+	var cfg SwitchFormatterConfig
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return nil, err
+	}
+	return NewSwitchFormatterWithConfig(&cfg)
+}
+
+// LoadSwitchFormatterFile load the SwitchFormatter from the JSON file. see LoadSwitchFormatter()
+func LoadSwitchFormatterFile(file string) (*SwitchFormatter, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	return LoadSwitchFormatter(fh)
+}
@@ -0,0 +1,175 @@
+package slog
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyslogNilValue the NILVALUE of RFC 5424
+const SyslogNilValue = "-"
+
+// DefaultSyslogSDID default SD-ID of the STRUCTURED-DATA element for the Record.Fields
+var DefaultSyslogSDID = "fields@32473"
+
+var sdParamReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// SyslogFormatter definition. format the record to an RFC 5424 syslog message.
+//
+// eg:
+// 	<11>1 2021-03-01T12:00:00.000000Z myhost myapp 1234 application [fields@32473 uid="23"] error message
+//
+// - PRI is calculated by Facility and the syslog severity of the record level
+// - MSGID is the record channel
+// - STRUCTURED-DATA is built from the Record.Fields, the params are sorted by name
+//
+// NOTICE: the formatted message not contains an trailing newline.
+type SyslogFormatter struct {
+	// Facility code. default is 1(user-level)
+	Facility int
+	// Hostname default is os.Hostname()
+	Hostname string
+	// AppName default is the program name
+	AppName string
+	// ProcID default is the process id
+	ProcID string
+	// SDID the SD-ID for the Record.Fields. default is DefaultSyslogSDID
+	SDID string
+	// TimeFormat the time format layout. default is RFC3339 with microseconds
+	TimeFormat string
+	// MessageFormatter format the MSG part by the formatter, and the STRUCTURED-DATA will be NILVALUE.
+	// eg: use an CEFFormatter for send the CEF events by syslog
+	MessageFormatter Formatter
+}
+
+// NewSyslogFormatter create new SyslogFormatter
+func NewSyslogFormatter(fn ...func(*SyslogFormatter)) *SyslogFormatter {
+	host, _ := os.Hostname()
+	f := &SyslogFormatter{
+		Facility:   1,
+		Hostname:   host,
+		AppName:    filepath.Base(os.Args[0]),
+		ProcID:     strconv.Itoa(os.Getpid()),
+		SDID:       DefaultSyslogSDID,
+		TimeFormat: "2006-01-02T15:04:05.000000Z07:00",
+	}
+
+	if len(fn) > 0 {
+		fn[0](f)
+	}
+
+	return f
+}
+
+// Configure current formatter
+func (f *SyslogFormatter) Configure(fn func(*SyslogFormatter)) *SyslogFormatter {
+	fn(f)
+	return f
+}
+
+// Format an log record
+func (f *SyslogFormatter) Format(r *Record) ([]byte, error) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	buf := make([]byte, 0, 256)
+	buf = append(buf, '<')
+	buf = strconv.AppendInt(buf, int64(f.Facility*8+r.Level.SyslogSeverity()), 10)
+	buf = append(buf, ">1 "...)
+	buf = append(buf, r.Time.Format(f.TimeFormat)...)
+	buf = append(buf, ' ')
+	buf = appendSyslogHeader(buf, f.Hostname, 255)
+	buf = append(buf, ' ')
+	buf = appendSyslogHeader(buf, f.AppName, 48)
+	buf = append(buf, ' ')
+	buf = appendSyslogHeader(buf, f.ProcID, 128)
+	buf = append(buf, ' ')
+	buf = appendSyslogHeader(buf, r.Channel, 32)
+	buf = append(buf, ' ')
+
+	if f.MessageFormatter != nil {
+		msg, err := f.MessageFormatter.Format(r)
+		if err != nil {
+			return nil, err
+		}
+
+		buf = append(buf, SyslogNilValue+" "...)
+		return append(buf, strings.TrimRight(string(msg), "\n")...), nil
+	}
+
+	buf = f.appendSD(buf, r.Fields)
+	if r.Message != "" {
+		buf = append(buf, ' ')
+		buf = append(buf, r.Message...)
+	}
+	return buf, nil
+}
+
+// STRUCTURED-DATA: [SD-ID PARAM-NAME="PARAM-VALUE" ...]
+func (f *SyslogFormatter) appendSD(buf []byte, fields M) []byte {
+	if len(fields) == 0 {
+		return append(buf, SyslogNilValue...)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf = append(buf, '[')
+	buf = appendSDName(buf, f.SDID)
+	for _, name := range names {
+		buf = append(buf, ' ')
+		buf = appendSDName(buf, name)
+		buf = append(buf, '=', '"')
+		buf = append(buf, sdParamReplacer.Replace(EncodeToString(fields[name]))...)
+		buf = append(buf, '"')
+	}
+	return append(buf, ']')
+}
+
+// the header field must be printable US-ASCII, and has the max length.
+func appendSyslogHeader(buf []byte, s string, maxLen int) []byte {
+	if s == "" {
+		return append(buf, SyslogNilValue...)
+	}
+
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < 33 || s[i] > 126 {
+			buf = append(buf, '_')
+		} else {
+			buf = append(buf, s[i])
+		}
+	}
+	return buf
+}
+
+// the SD-NAME is 1-32 printable US-ASCII, except '=', ' ', ']', '"'
+func appendSDName(buf []byte, s string) []byte {
+	if s == "" {
+		return append(buf, '_')
+	}
+
+	if len(s) > 32 {
+		s = s[:32]
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			buf = append(buf, '_')
+		} else {
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
@@ -1,9 +1,20 @@
 package slog_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gookit/color"
+	"github.com/stretchr/testify/assert"
 	"github.com/tomorrowsky/slog"
 	"github.com/tomorrowsky/slog/handler"
 )
@@ -14,6 +25,51 @@ func TestNewTextFormatter(t *testing.T) {
 	fmt.Println(lf.FieldMap())
 }
 
+func TestTextFormatter_Template(t *testing.T) {
+	r := &slog.Record{
+		Time:    time.Date(2021, 3, 1, 20, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+		Level:   slog.WarnLevel,
+		Channel: "app",
+		Message: "user login",
+		Fields:  slog.M{"user": slog.M{"id": 23, "name": "inhere"}},
+	}
+
+	f := slog.NewTextFormatter("{{datetime|tz:UTC}} [{{level|lower|pad:7}}] [{{level|pad:-7}}] {{message|upper|trim:4}}|{{channel|trim:-2}} uid={{fields.user.id}}{{?data}} data={{data|json}}{{/data}}\n")
+	f.TimeFormat = "2006-01-02 15:04:05"
+	assert.Equal(t, slog.StringMap{
+		"datetime": "{{datetime|tz:UTC}}",
+		"level":    "{{level|lower|pad:7}}",
+		"message":  "{{message|upper|trim:4}}",
+		"channel":  "{{channel|trim:-2}}",
+		"fields":   "{{fields.user.id}}",
+		"data":     "{{?data}}",
+	}, f.FieldMap())
+
+	bs, err := f.Format(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "2021-03-01 12:00:00 [warning] [WARNING] USER|pp uid=23\n", string(bs))
+
+	r.Data = slog.M{"ip": "127.0.0.1"}
+	bs, _ = f.Format(r)
+	assert.Equal(t, `2021-03-01 12:00:00 [warning] [WARNING] USER|pp uid=23 data={"ip":"127.0.0.1"}`+"\n", string(bs))
+
+	// nested sections, the unknown or invalid tags are output as is
+	f.SetTemplate("{{?fields.user}}{{fields.user.name}}{{?fields.role}} {{fields.role}}{{/fields.role}}{{/fields.user}} {{level|unknown}} {{/data}} {{ip}}.")
+	bs, _ = f.Format(r)
+	assert.Equal(t, "inhere {{level|unknown}} {{/data}} {{ip}}.", string(bs))
+
+	// the empty tags are output as is
+	f.SetTemplate("a {{ }} b {{message}}")
+	bs, _ = f.Format(r)
+	assert.Equal(t, "a {{ }} b user login", string(bs))
+
+	// the missing built-in fields render empty
+	f.SetTemplate("{{datetime|format:15:04}} {{data.ip}}{{data.port}}{{extra}}")
+	bs, _ = f.Format(r)
+	assert.Equal(t, "20:00 127.0.0.1", string(bs))
+	assert.Equal(t, "{{data.ip}}", f.FieldMap()["data"])
+}
+
 func TestJSONFormatter(t *testing.T) {
 	l := slog.New()
 
@@ -49,3 +105,781 @@ func TestJSONFormatter(t *testing.T) {
 		SetExtra(slog.M{"ext1": "val1"}).
 		Info("info message and PrettyPrint is TRUE")
 }
+
+func TestJSONFormatter_Order(t *testing.T) {
+	r := &slog.Record{
+		Time:    time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+		Level:   slog.InfoLevel,
+		Channel: "app",
+		Message: "user login",
+		Data:    slog.M{"b": 2, "a": slog.M{"y": 1, "x": errors.New("fail")}},
+	}
+	r = r.WithField("zone", "cn").WithFields(slog.M{"uid": 23, "name": "inhere"})
+	r.AddField("level", "x")
+	r.Fields["added"] = true
+	assert.Equal(t, []string{"zone", "name", "uid", "level", "added"}, r.FieldKeys())
+
+	f := slog.NewJSONFormatter(func(f *slog.JSONFormatter) {
+		f.Fields = []string{slog.FieldKeyMessage, slog.FieldKeyLevel, slog.FieldKeyDatetime}
+		f.TimeFormat = time.RFC3339
+	})
+	want := `{"message":"user login","level":"INFO","datetime":"2021-03-01T12:00:00Z","zone":"cn","name":"inhere","uid":23,"fields.level":"x","added":true}` + "\n"
+	for i := 0; i < 5; i++ {
+		bs, err := f.Format(r)
+		assert.NoError(t, err)
+		assert.Equal(t, want, string(bs))
+	}
+
+	f.SortFields = true
+	bs, _ := f.Format(r)
+	assert.Equal(t, `{"message":"user login","level":"INFO","datetime":"2021-03-01T12:00:00Z","added":true,"fields.level":"x","name":"inhere","uid":23,"zone":"cn"}`+"\n", string(bs))
+
+	// the map in text output
+	assert.Equal(t, `{"a":{"x":"fail","y":1},"b":2}`, slog.EncodeToString(r.Data))
+	assert.Equal(t, `{"a":{"x":"fail","y":1},"b":2}`, r.Data.String())
+	assert.Equal(t, `a.x=fail a.y=1 b=2`, slog.EncodeToKVString(r.Data))
+
+	tf := slog.NewTextFormatter("{{message}} {{data}}")
+	tf.EncodeFunc = slog.EncodeToKVString
+	bs, _ = tf.Format(r)
+	assert.Equal(t, `user login a.x=fail a.y=1 b=2`, string(bs))
+}
+
+type jsonPoint struct {
+	X, Y int
+	Tag  string `json:"tag,omitempty"`
+}
+
+type jsonRaw string
+
+func (v jsonRaw) MarshalJSON() ([]byte, error) {
+	return []byte(v), nil
+}
+
+func TestJSONFormatter_Encoder(t *testing.T) {
+	data := slog.M{
+		"str":    "a\"b\\c\nd\re\tf\x01<g>&h\u2028\xff中文",
+		"bad":    "\xc3\x28\xe2\x82",
+		"int":    -23,
+		"int8":   int8(8),
+		"uint64": uint64(1 << 63),
+		"float":  1.5,
+		"small":  1e-7,
+		"big":    1e21,
+		"f32":    float32(0.1),
+		"bool":   true,
+		"nil":    nil,
+		"bytes":  []byte("hello"),
+		"time":   time.Date(2021, 3, 1, 12, 0, 0, 1500, time.FixedZone("CST", 8*3600)),
+		"list":   []interface{}{1, "a", nil, slog.M{"k": "v"}},
+		"strs":   []string{"x", "y"},
+		"ints":   []int{1, 2},
+		"smap":   map[string]string{"b": "2", "a": "1"},
+		"nested": map[string]interface{}{"z": 1, "a": slog.M{"b": false}},
+		"struct": jsonPoint{X: 1, Y: 2},
+		"ptr":    &jsonPoint{Tag: "<t>"},
+		"raw":    jsonRaw(`{ "a" : [1, 2] }`),
+		"empty":  slog.M{},
+	}
+	r := &slog.Record{Level: slog.InfoLevel, Message: "<msg>", Data: data}
+
+	f := slog.NewJSONFormatter(func(f *slog.JSONFormatter) {
+		f.Fields = []string{slog.FieldKeyMessage, slog.FieldKeyData, slog.FieldKeyExtra}
+		f.EscapeHTML = true
+	})
+
+	// same as the encoding/json
+	want, err := json.Marshal(map[string]interface{}{"message": r.Message, "data": data, "extra": r.Extra})
+	assert.NoError(t, err)
+	var wantMap, gotMap interface{}
+	assert.NoError(t, json.Unmarshal(want, &wantMap))
+
+	bs, err := f.Format(r)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(bs, &gotMap))
+	assert.Equal(t, wantMap, gotMap)
+	// the keys order is different.
+	// the encoding/json v2(GOEXPERIMENT=jsonv2) outputs the U+FFFD of the invalid UTF-8 without escape
+	wantData, _ := json.Marshal(data)
+	wantStr := strings.Replace(string(wantData), "\ufffd", `\ufffd`, -1)
+	assert.Contains(t, string(bs), `{"message":"\u003cmsg\u003e","data":`+wantStr+`,"extra":null}`)
+	assert.Contains(t, string(bs), `\u2028\ufffd中文"`)
+	assert.Contains(t, string(bs), `"bad":"\ufffd(\ufffd\ufffd"`)
+
+	// the HTML chars are not escaped by default
+	f.EscapeHTML = false
+	bs, _ = f.Format(&slog.Record{Message: "<a&b>", Fields: slog.M{"raw": jsonRaw(`"<x>"`)}})
+	assert.Equal(t, `{"message":"<a&b>","data":null,"extra":null,"raw":"<x>"}`+"\n", string(bs))
+
+	// the error and fmt.Stringer are encoded as string
+	var nilErr *json.SyntaxError
+	bs, _ = f.Format(&slog.Record{Data: slog.M{"err": errors.New("fail"), "level": slog.WarnLevel, "nil": nilErr}})
+	assert.Equal(t, `{"message":"","data":{"err":"fail","level":"WARNING","nil":null},"extra":null}`+"\n", string(bs))
+}
+
+func TestDevFormatter(t *testing.T) {
+	f := slog.NewDevFormatter(func(f *slog.DevFormatter) {
+		f.Width = 80
+		f.MaxCallerWidth = 16
+		f.EnableColor = false
+	})
+
+	tm := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := &slog.Record{
+		Time:    tm,
+		Level:   slog.WarnLevel,
+		Channel: "app",
+		Message: "user login failed\nthe second line of the message is long and should be wrapped at the width",
+		Caller:  &runtime.Frame{File: "/work/src/app/handler/user.go", Line: 48},
+		Data:    slog.M{"ip": "127.0.0.1", "user": slog.M{"id": 1}},
+	}
+	r.AddField("uid", 23).AddField("error", errors.New("not found")).AddField("name", "in here")
+
+	bs, err := f.Format(r)
+	assert.NoError(t, err)
+	assert.Equal(t, `12:00:00.000 WARNING app        user login failed               …dler/user.go:48
+                                the second line of the message is long and
+                                should be wrapped at the width
+                                uid   = 23
+                                error = not found
+                                name  = "in here"
+                                data:
+                                  ip   = 127.0.0.1
+                                  user:
+                                    id = 1
+`, string(bs))
+
+	// the relative time
+	bs, _ = f.Format(&slog.Record{Time: tm.Add(12 * time.Millisecond), Level: slog.InfoLevel, Channel: "app", Message: "ok"})
+	assert.Equal(t, "+12ms        INFO    app        ok\n", string(bs))
+	bs, _ = f.Format(&slog.Record{Time: tm.Add(1512 * time.Millisecond), Level: slog.InfoLevel, Channel: "app", Message: "ok"})
+	assert.Equal(t, "+1.50s       INFO    app        ok\n", string(bs))
+
+	// the width from env
+	f.Width = 0
+	assert.NoError(t, os.Setenv("COLUMNS", "50"))
+	defer os.Unsetenv("COLUMNS")
+	bs, _ = f.Format(&slog.Record{Time: tm, Level: slog.InfoLevel, Channel: "app", Message: "ok", Caller: r.Caller})
+	assert.Equal(t, "+0ms         INFO    app        ok\n                                …dler/user.go:48\n", string(bs))
+
+	// the width is detected once
+	assert.NoError(t, os.Setenv("COLUMNS", "200"))
+	bs, _ = f.Format(&slog.Record{Time: tm, Level: slog.InfoLevel, Channel: "app", Message: "ok", Caller: r.Caller})
+	assert.Equal(t, "+0ms         INFO    app        ok\n                                …dler/user.go:48\n", string(bs))
+
+	// the color on not a terminal
+	old := color.ForceSetColorLevel(0)
+	defer color.ForceSetColorLevel(old)
+	for _, force := range []bool{false, true} {
+		f = slog.NewDevFormatter(func(f *slog.DevFormatter) {
+			f.EnableColor = true
+			f.ForceColor = force
+		})
+		bs, _ = f.Format(&slog.Record{Time: tm, Level: slog.InfoLevel, Channel: "app", Message: "ok"})
+		assert.Equal(t, force, strings.Contains(string(bs), "\x1b["))
+	}
+}
+
+func TestThemeColor(t *testing.T) {
+	c := slog.MustThemeColor("bold #ff8700")
+	assert.Equal(t, "1;38;2;255;135;0", c.Code(slog.ColorLevelRGB))
+	assert.Equal(t, "1;38;5;208", c.Code(slog.ColorLevel256))
+	assert.Equal(t, "1;93", c.Code(slog.ColorLevel16))
+	assert.Equal(t, "", c.Code(slog.ColorLevelNone))
+
+	c = slog.MustThemeColor("244")
+	assert.Equal(t, "38;5;244", c.Code(slog.ColorLevelRGB))
+	assert.Equal(t, "37", c.Code(slog.ColorLevel16))
+	assert.Equal(t, "94", slog.MustThemeColor("lightBlue").Code(slog.ColorLevel256))
+	assert.True(t, slog.MustThemeColor("").IsEmpty())
+
+	_, err := slog.NewThemeColor("unknown")
+	assert.Equal(t, slog.ErrThemeColor, err)
+	_, err = slog.NewThemeColor("#ff")
+	assert.Equal(t, slog.ErrThemeColor, err)
+}
+
+func TestLoadTheme(t *testing.T) {
+	th, err := slog.LoadTheme(bytes.NewBufferString(`{
+		"name": "mine",
+		"base": "light",
+		"levels": {"error": "bold red", "warn": "#ffaf00"},
+		"caller": "underscore blue"
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "mine", th.Name)
+	assert.Equal(t, "1;31", th.LevelColor(slog.ErrorLevel).Code(slog.ColorLevelRGB))
+	assert.Equal(t, "#ffaf00", th.LevelColor(slog.WarnLevel).String())
+	assert.Equal(t, "4;34", th.Caller.Code(slog.ColorLevel256))
+	// from the base
+	assert.Equal(t, slog.LightTheme.Time, th.Time)
+	assert.Equal(t, slog.LightTheme.LevelColor(slog.InfoLevel), th.LevelColor(slog.InfoLevel))
+	// the base is not changed
+	assert.Equal(t, "#d70000", slog.LightTheme.LevelColor(slog.ErrorLevel).String())
+
+	_, err = slog.LoadTheme(bytes.NewBufferString(`{"time": "nope"}`))
+	assert.Equal(t, slog.ErrThemeColor, err)
+	_, err = slog.LoadTheme(bytes.NewBufferString(`{"base": "nope"}`))
+	assert.Error(t, err)
+	_, err = slog.LoadThemeFile("testdata/not-exists.json")
+	assert.Error(t, err)
+}
+
+func TestTextFormatter_Theme(t *testing.T) {
+	old := color.ForceColor()
+	defer color.ForceSetColorLevel(old)
+
+	newFormatter := func() *slog.TextFormatter {
+		f := slog.NewTextFormatter("{{datetime}} {{level}} {{caller}} {{message}} {{uid}} {{ok}}")
+		f.EnableColor = true
+		f.Theme = &slog.Theme{
+			Levels: map[slog.Level]slog.ThemeColor{slog.InfoLevel: slog.MustThemeColor("green")},
+			Time:   slog.MustThemeColor("gray"),
+			Caller: slog.MustThemeColor("#0000ff"),
+			Number: slog.MustThemeColor("208"),
+		}
+		f.TimeFormat = "15:04"
+		return f
+	}
+
+	r := &slog.Record{
+		Time:    time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+		Level:   slog.InfoLevel,
+		Message: "hi",
+		Caller:  &runtime.Frame{File: "/app/main.go", Line: 3, Function: "main.main"},
+		Fields:  slog.M{"uid": 23, "ok": true},
+	}
+	f := newFormatter()
+	bs, err := f.Format(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "\x1b[90m12:00\x1b[0m \x1b[32mINFO\x1b[0m \x1b[38;2;0;0;255mmain.go:3main\x1b[0m \x1b[32mhi\x1b[0m \x1b[38;5;208m23\x1b[0m true", string(bs))
+
+	// the color level is detected once
+	color.ForceSetColorLevel(0)
+	bs, _ = f.Format(r)
+	assert.Equal(t, "\x1b[90m12:00\x1b[0m \x1b[32mINFO\x1b[0m \x1b[38;2;0;0;255mmain.go:3main\x1b[0m \x1b[32mhi\x1b[0m \x1b[38;5;208m23\x1b[0m true", string(bs))
+
+	// not a terminal
+	bs, _ = newFormatter().Format(r)
+	assert.Equal(t, "12:00 INFO main.go:3main hi 23 true", string(bs))
+
+	// force color, degrade to the 16 colors
+	f = newFormatter()
+	f.ForceColor = true
+	bs, _ = f.Format(r)
+	assert.Equal(t, "\x1b[90m12:00\x1b[0m \x1b[32mINFO\x1b[0m \x1b[94mmain.go:3main\x1b[0m \x1b[32mhi\x1b[0m \x1b[93m23\x1b[0m true", string(bs))
+
+	// the ColorTheme
+	color.ForceColor()
+	f = slog.NewTextFormatter("{{level}} {{message}}")
+	f.EnableColor = true
+	bs, _ = f.Format(r)
+	assert.Equal(t, "\x1b[32mINFO\x1b[0m \x1b[32mhi\x1b[0m", string(bs))
+
+	// NO_COLOR
+	color.Enable = false
+	defer func() { color.Enable = true }()
+	bs, _ = newFormatter().Format(r)
+	assert.Equal(t, "12:00 INFO main.go:3main hi 23 true", string(bs))
+	f = slog.NewTextFormatter("{{level}} {{message}}")
+	f.EnableColor = true
+	bs, _ = f.Format(r)
+	assert.Equal(t, "INFO hi", string(bs))
+
+	// disable color
+	f = newFormatter()
+	f.EnableColor = false
+	f.ForceColor = true
+	bs, _ = f.Format(r)
+	assert.Equal(t, "12:00 INFO main.go:3main hi 23 true", string(bs))
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	f := slog.NewLogfmtFormatter()
+	r := &slog.Record{
+		Time:    time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+		Level:   slog.InfoLevel,
+		Channel: "app",
+		Message: `user "tom" login, a=b`,
+		Data: slog.M{
+			"uid":  23,
+			"user": slog.M{"name": "tom", "roles": map[string]string{"admin": "yes"}},
+		},
+		Extra:  slog.M{"err": errors.New("line1\nline2"), "empty": ""},
+		Fields: slog.M{"ip": "127.0.0.1", "level": "custom", "bad key": true},
+	}
+
+	bts, err := f.Format(r)
+	assert.NoError(t, err)
+	assert.Equal(t, `datetime=2021-03-01T12:00:00Z channel=app level=INFO message="user \"tom\" login, a=b" `+
+		`data.uid=23 data.user.name=tom data.user.roles.admin=yes extra.empty="" extra.err="line1\nline2" `+
+		`bad_key=true ip=127.0.0.1 fields.level=custom`+"\n", string(bts))
+
+	// with Aliases and custom Fields
+	f = slog.NewLogfmtFormatter(func(f *slog.LogfmtFormatter) {
+		f.Fields = []string{slog.FieldKeyLevel, "ip", slog.FieldKeyMessage}
+		f.Aliases = slog.StringMap{"level": "lvl", "message": "msg"}
+	})
+
+	bts, err = f.Format(r)
+	assert.NoError(t, err)
+	assert.Equal(t, `lvl=INFO ip=127.0.0.1 msg="user \"tom\" login, a=b" bad_key=true level=custom`+"\n", string(bts))
+}
+
+func TestGELFFormatter(t *testing.T) {
+	f := slog.NewGELFFormatter(func(f *slog.GELFFormatter) {
+		f.Host = "example.org"
+	})
+
+	bts, err := f.Format(&slog.Record{
+		Time:    time.Unix(1614600000, 123e6),
+		Level:   slog.NoticeLevel,
+		Channel: "app",
+		Message: "notice message",
+		Fields:  slog.M{"bad key": true, "err": errors.New("an error")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"_bad_key":"true","_channel":"app","_err":"an error","host":"example.org",`+
+		`"level":5,"short_message":"notice message","timestamp":1614600000.123,"version":"1.1"}`, string(bts))
+}
+
+func TestSyslogFormatter(t *testing.T) {
+	f := slog.NewSyslogFormatter(func(f *slog.SyslogFormatter) {
+		f.Facility = 16
+		f.Hostname = "my host"
+		f.AppName = ""
+		f.ProcID = "1"
+	})
+
+	bts, err := f.Format(&slog.Record{
+		Time:    time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+		Level:   slog.PanicLevel,
+		Message: "panic message",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "<128>1 2021-03-01T12:00:00.000000Z my_host - 1 - - panic message", string(bts))
+}
+
+func TestOTelFormatter(t *testing.T) {
+	f := slog.NewOTelFormatter()
+	ctx := slog.ContextWithTrace(context.Background(), slog.TraceContext{
+		TraceID: "5b8efff798038103d269b633813fc60c",
+		SpanID:  "eee19b7ec3c1b174",
+		Sampled: true,
+	})
+
+	r := &slog.Record{
+		Time:    time.Unix(1614600000, 0),
+		Level:   slog.WarnLevel,
+		Channel: "app",
+		Message: "warn message",
+		Ctx:     ctx,
+		Data:    slog.M{"uid": 23, "user": slog.M{"name": "tom"}},
+		Fields:  slog.M{"tags": []string{"a", "b"}},
+	}
+
+	lr := f.LogRecord(r)
+	assert.Equal(t, "1614600000000000000", lr.TimeUnixNano)
+	assert.Equal(t, 13, lr.SeverityNumber)
+	assert.Equal(t, "WARNING", lr.SeverityText)
+	assert.Equal(t, "warn message", *lr.Body.StringValue)
+	assert.Equal(t, "5b8efff798038103d269b633813fc60c", lr.TraceID)
+	assert.Equal(t, "eee19b7ec3c1b174", lr.SpanID)
+	assert.Equal(t, uint32(1), lr.Flags)
+
+	bts, err := f.Format(r)
+	assert.NoError(t, err)
+
+	str := string(bts)
+	assert.Contains(t, str, `{"key":"log.channel","value":{"stringValue":"app"}}`)
+	assert.Contains(t, str, `{"key":"uid","value":{"intValue":"23"}}`)
+	assert.Contains(t, str, `{"key":"user","value":{"kvlistValue":{"values":[{"key":"name","value":{"stringValue":"tom"}}]}}}`)
+	assert.Contains(t, str, `{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"stringValue":"b"}]}}}`)
+	assert.True(t, json.Valid(bts))
+
+	// custom TraceExtractor
+	old := slog.TraceExtractor
+	defer func() { slog.TraceExtractor = old }()
+	slog.TraceExtractor = func(ctx context.Context) (slog.TraceContext, bool) {
+		return slog.TraceContext{}, false
+	}
+	assert.Equal(t, "", f.LogRecord(r).TraceID)
+}
+
+func TestECSFormatter(t *testing.T) {
+	f := slog.NewECSFormatter()
+	r := &slog.Record{
+		Time:    time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+		Level:   slog.ErrorLevel,
+		Channel: "app",
+		Message: "request failed",
+		Caller:  &runtime.Frame{File: "/src/app/main.go", Line: 42, Function: "main.handle"},
+		Data:    slog.M{"user": slog.M{"id": 1}},
+		Fields: slog.M{
+			"err":         errors.New("connection refused"),
+			"other":       errors.New("other error"),
+			"http.method": "GET",
+			"http.status": 500,
+		},
+	}
+
+	bs, err := f.Format(r)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"@timestamp": "2024-01-02T03:04:05.123456789Z",
+		"ecs": {"version": "8.11.0"},
+		"log": {
+			"level": "ERROR",
+			"logger": "app",
+			"origin": {"file": {"name": "main.go", "line": 42}, "function": "main.handle"}
+		},
+		"message": "request failed",
+		"error": {"message": "connection refused", "type": "*errors.errorString"},
+		"http": {"method": "GET", "status": 500},
+		"other": "other error",
+		"data": {"user": {"id": 1}}
+	}`, string(bs))
+
+	// custom namespaces
+	f.DataNamespace = ""
+	f.FieldsNamespace = "labels"
+	r.Fields = slog.M{"env": "prod"}
+	doc := f.Document(r)
+	assert.Equal(t, slog.M{"env": "prod"}, doc["labels"])
+	assert.Equal(t, slog.M{"id": 1}, doc["user"])
+}
+
+func TestGCPFormatter(t *testing.T) {
+	f := slog.NewGCPFormatter(func(f *slog.GCPFormatter) {
+		f.ProjectID = "my-project"
+		f.Labels = map[string]string{"env": "prod"}
+		f.LabelFields = []string{"tenant"}
+	})
+
+	ctx := slog.ContextWithTrace(context.Background(), slog.TraceContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Sampled: true,
+	})
+	r := &slog.Record{
+		Ctx:     ctx,
+		Time:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Level:   slog.NoticeLevel,
+		Channel: "app",
+		Message: "request done",
+		Caller:  &runtime.Frame{File: "/src/app/main.go", Line: 42, Function: "main.handle"},
+		Fields: slog.M{
+			"tenant":               "acme",
+			slog.GCPKeyHTTPRequest: &slog.GCPHTTPRequest{RequestMethod: "GET", Status: 200, Latency: "0.125s"},
+			"message":              "field message",
+			"user":                 23,
+		},
+	}
+
+	bs, err := f.Format(r)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"severity": "NOTICE",
+		"time": "2024-01-02T03:04:05Z",
+		"message": "request done",
+		"fields.message": "field message",
+		"user": 23,
+		"httpRequest": {"requestMethod": "GET", "status": 200, "latency": "0.125s"},
+		"logging.googleapis.com/labels": {"env": "prod", "channel": "app", "tenant": "acme"},
+		"logging.googleapis.com/sourceLocation": {"file": "/src/app/main.go", "line": "42", "function": "main.handle"},
+		"logging.googleapis.com/trace": "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		"logging.googleapis.com/spanId": "00f067aa0ba902b7",
+		"logging.googleapis.com/trace_sampled": true
+	}`, string(bs))
+
+	// report the error with stack trace
+	r = &slog.Record{
+		Level:   slog.ErrorLevel,
+		Message: "query failed",
+		Caller:  &runtime.Frame{File: "/src/app/db.go", Line: 7, Function: "main.query"},
+		Fields:  slog.M{"err": errors.New("connection refused")},
+	}
+	entry := f.Entry(r)
+	assert.Equal(t, "ERROR", entry["severity"])
+	assert.Equal(t, "connection refused", entry["err"])
+	assert.Contains(t, entry["@type"], "ReportedErrorEvent")
+	assert.Equal(t, "query failed: connection refused", entry["message"])
+	assert.Equal(t, slog.M{"reportLocation": slog.M{
+		"filePath":     "/src/app/db.go",
+		"lineNumber":   7,
+		"functionName": "main.query",
+	}}, entry["context"])
+
+	// the error with stack
+	r.Fields = slog.M{"err": stackError{msg: "connection refused", stack: "main.query\n\t/src/app/db.go:7"}}
+	entry = f.Entry(r)
+	assert.Equal(t, "query failed: connection refused\nmain.query\n\t/src/app/db.go:7", entry["message"])
+	assert.Nil(t, entry["context"])
+
+	assert.Equal(t, "DEBUG", slog.TraceLevel.GCPSeverity())
+	assert.Equal(t, "EMERGENCY", slog.PanicLevel.GCPSeverity())
+}
+
+// stackError an error with stack, like the github.com/pkg/errors
+type stackError struct {
+	msg   string
+	stack string
+}
+
+func (e stackError) Error() string {
+	return e.msg
+}
+
+func (e stackError) Format(s fmt.State, verb rune) {
+	_, _ = io.WriteString(s, e.msg)
+	if verb == 'v' && s.Flag('+') {
+		_, _ = io.WriteString(s, "\n"+e.stack)
+	}
+}
+
+func TestCEFFormatter(t *testing.T) {
+	f := slog.NewCEFFormatter(func(f *slog.CEFFormatter) {
+		f.Vendor = "My|Company"
+		f.Product = "MyApp"
+		f.Version = "1.0"
+	})
+	r := &slog.Record{
+		Time:    time.Unix(1614571200, 123000000),
+		Level:   slog.WarnLevel,
+		Channel: "audit",
+		Message: "user login\nfailed",
+		Data:    slog.M{"user": slog.M{"name": `a\b`}},
+		Fields:  slog.M{"signature_id": "user.login", "query": "a=b|c", "err": errors.New("bad password")},
+	}
+
+	bs, err := f.Format(r)
+	assert.NoError(t, err)
+	assert.Equal(t, `CEF:0|My\|Company|MyApp|1.0|user.login|user login failed|5|rt=1614571200123 cat=audit err=bad password query=a\=b|c user.name=a\\b`+"\n", string(bs))
+
+	// the signature ID is the channel by default
+	r.Fields = slog.M{"msg": "line1\nline2"}
+	bs, err = f.Format(r)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bs), `|1.0|audit|`)
+	assert.Contains(t, string(bs), `msg=line1\nline2`)
+}
+
+func TestLEEFFormatter(t *testing.T) {
+	f := slog.NewLEEFFormatter(func(f *slog.LEEFFormatter) {
+		f.Vendor = "MyCompany"
+		f.Product = "MyApp"
+	})
+	r := &slog.Record{
+		Time:    time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+		Level:   slog.ErrorLevel,
+		Channel: "audit",
+		Message: "user login",
+		Fields:  slog.M{"event_id": "user.login", "note": "a\tb\nc", "uid": 23},
+	}
+
+	bs, err := f.Format(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "LEEF:2.0|MyCompany|MyApp|1.0|user.login|x09|devTime=Mar 01 2021 12:00:00.000 UTC\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS z"+
+		"\tsev=7\tcat=audit\tmsg=user login\tnote=a\\\tb\\nc\tuid=23\n", string(bs))
+
+	f.Delimiter = '^'
+	bs, err = f.Format(r)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bs), "|user.login|^|devTime=")
+	assert.Contains(t, string(bs), "^sev=7^cat=audit^")
+}
+
+func TestSyslogFormatter_MessageFormatter(t *testing.T) {
+	f := slog.NewSyslogFormatter(func(f *slog.SyslogFormatter) {
+		f.Hostname = "myhost"
+		f.AppName = "myapp"
+		f.ProcID = "1234"
+		f.MessageFormatter = slog.NewCEFFormatter()
+	})
+
+	r := &slog.Record{
+		Time:    time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+		Level:   slog.ErrorLevel,
+		Channel: "audit",
+		Message: "user login",
+	}
+	bs, err := f.Format(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "<11>1 2021-03-01T12:00:00.000000Z myhost myapp 1234 audit - CEF:0|slog|slog|1.0|audit|user login|7|rt=1614600000000 cat=audit", string(bs))
+}
+
+func TestBinaryFormatters(t *testing.T) {
+	r := &slog.Record{
+		Time:    time.Unix(1614600000, 123456000),
+		Level:   slog.WarnLevel,
+		Channel: "app",
+		Message: "disk is full",
+		Caller:  &runtime.Frame{File: "/src/app/main.go", Line: 42, Function: "main.check"},
+		Data:    slog.M{"disk": slog.M{"path": "/", "used": 0.95}},
+		Fields:  slog.M{"host": "web-01"},
+	}
+
+	tests := []struct {
+		name   string
+		format slog.Formatter
+		newDec func(rd io.Reader) *slog.RecordDecoder
+	}{
+		{"msgpack", slog.NewMsgpackFormatter(), slog.NewMsgpackDecoder},
+		{"cbor", slog.NewCBORFormatter(), slog.NewCBORDecoder},
+	}
+
+	for _, tt := range tests {
+		buf := new(bytes.Buffer)
+		for i := 0; i < 2; i++ {
+			bs, err := tt.format.Format(r)
+			assert.NoError(t, err)
+			buf.Write(bs)
+		}
+		// the length prefix
+		assert.Equal(t, uint32(buf.Len()/2-4), uint32(buf.Bytes()[2])<<8|uint32(buf.Bytes()[3]), tt.name)
+
+		dec := tt.newDec(buf)
+		for i := 0; i < 2; i++ {
+			got, err := dec.Decode()
+			assert.NoError(t, err, tt.name)
+			assert.True(t, r.Time.Equal(got.Time), tt.name)
+			assert.Equal(t, slog.WarnLevel, got.Level)
+			assert.Equal(t, "app", got.Channel)
+			assert.Equal(t, "disk is full", got.Message)
+			assert.Equal(t, *r.Caller, *got.Caller)
+			assert.Equal(t, slog.M{"disk": map[string]interface{}{"path": "/", "used": 0.95}}, got.Data)
+			assert.Equal(t, slog.M{"host": "web-01"}, got.Fields)
+			assert.Nil(t, got.Extra)
+		}
+
+		_, err := dec.Decode()
+		assert.Equal(t, io.EOF, err, tt.name)
+	}
+
+	// without the framing
+	f := slog.NewCBORFormatter(func(f *slog.CBORFormatter) {
+		f.Framed = false
+	})
+	bs, err := f.Format(&slog.Record{Time: r.Time, Level: slog.InfoLevel, Message: "a"})
+	assert.NoError(t, err)
+
+	dec := slog.NewCBORDecoder(bytes.NewReader(append(bs, bs...)))
+	dec.Framed = false
+	for i := 0; i < 2; i++ {
+		got, err := dec.Decode()
+		assert.NoError(t, err)
+		assert.Equal(t, "a", got.Message)
+	}
+
+	// the truncated record
+	bs, _ = slog.NewMsgpackFormatter().Format(r)
+	_, err = slog.NewMsgpackDecoder(bytes.NewReader(bs[:len(bs)-1])).Decode()
+	assert.Equal(t, slog.ErrBinaryRecord, err)
+
+	// the corrupt frame size will not alloc the memory before read the data
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = slog.NewCBORDecoder(bytes.NewReader([]byte{0x03, 0xff, 0xff, 0xff, 0xa0})).Decode()
+	runtime.ReadMemStats(&after)
+	assert.Equal(t, slog.ErrBinaryRecord, err)
+	assert.True(t, after.TotalAlloc-before.TotalAlloc < 1<<20)
+}
+
+func TestSwitchFormatter(t *testing.T) {
+	f := slog.NewSwitchFormatter(slog.NewTextFormatter("[{{level}}] {{message}}\n"))
+	f.OnLevels(slog.NewTextFormatter("[{{level}}] {{message}} {{fline}}\n"), slog.ErrorLevel, slog.FatalLevel).
+		OnChannels(slog.NewTextFormatter("http: {{message}}\n"), "http").
+		OnMatch(func(r *slog.Record) bool { return r.Fields["slow"] == true }, slog.NewTextFormatter("slow: {{message}}\n"))
+
+	tests := []struct {
+		r    *slog.Record
+		want string
+	}{
+		{&slog.Record{Level: slog.InfoLevel, Message: "ok"}, "[INFO] ok\n"},
+		{&slog.Record{Level: slog.ErrorLevel, Message: "failed", Caller: &runtime.Frame{File: "/app/main.go", Line: 3, Function: "main.main"}}, "[ERROR] failed main.go:3\n"},
+		{&slog.Record{Level: slog.InfoLevel, Channel: "http", Message: "GET /"}, "http: GET /\n"},
+		{&slog.Record{Level: slog.InfoLevel, Message: "query", Fields: slog.M{"slow": true}}, "slow: query\n"},
+	}
+	for _, tt := range tests {
+		bs, err := f.Format(tt.r)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, string(bs))
+	}
+
+	// all the conditions must match
+	rule := &slog.SwitchRule{Levels: slog.Levels{slog.InfoLevel}, Channels: []string{"http"}}
+	assert.True(t, rule.IsMatch(&slog.Record{Level: slog.InfoLevel, Channel: "http"}))
+	assert.False(t, rule.IsMatch(&slog.Record{Level: slog.ErrorLevel, Channel: "http"}))
+	assert.False(t, rule.IsMatch(&slog.Record{Level: slog.InfoLevel, Channel: "app"}))
+
+	// use in the handler
+	buf := new(bytes.Buffer)
+	h := handler.NewIOWriterHandler(buf, slog.AllLevels)
+	h.SetFormatter(f)
+	l := slog.NewWithHandlers(h)
+	l.Info("info message")
+	l.Error("error message")
+	assert.Contains(t, buf.String(), "[INFO] info message\n[ERROR] error message formatter_test.go:")
+
+	// the nil formatter rule is skipped
+	f.OnLevels(nil, slog.WarnLevel)
+	bs, err := f.Format(&slog.Record{Level: slog.WarnLevel, Message: "warn"})
+	assert.NoError(t, err)
+	assert.Equal(t, "[WARNING] warn\n", string(bs))
+
+	// the default is the TextFormatter
+	f = slog.NewSwitchFormatter(nil)
+	assert.IsType(t, &slog.TextFormatter{}, f.Default)
+	f = &slog.SwitchFormatter{}
+	_, err = f.Format(&slog.Record{Level: slog.WarnLevel})
+	assert.Equal(t, slog.ErrNoSwitchFormatter, err)
+}
+
+func TestLoadSwitchFormatter(t *testing.T) {
+	f, err := slog.LoadSwitchFormatter(strings.NewReader(`{
+		"default": {"template": "[{{level}}] {{message}}\n"},
+		"rules": [
+			{"levels": ["error", "fatal"], "formatter": "json"},
+			{"channels": ["http"], "match": "has_error", "formatter": {"name": "logfmt"}}
+		]
+	}`))
+	assert.NoError(t, err)
+	assert.Len(t, f.Rules, 2)
+	assert.IsType(t, &slog.JSONFormatter{}, f.Rules[0].Formatter)
+	assert.Equal(t, slog.Levels{slog.ErrorLevel, slog.FatalLevel}, f.Rules[0].Levels)
+	assert.IsType(t, &slog.LogfmtFormatter{}, f.Rules[1].Formatter)
+
+	r := &slog.Record{Level: slog.InfoLevel, Channel: "http", Message: "ok"}
+	assert.IsType(t, &slog.TextFormatter{}, f.Select(r))
+	r.Fields = slog.M{"error": errors.New("timeout")}
+	assert.IsType(t, &slog.LogfmtFormatter{}, f.Select(r))
+
+	// the registered formatter
+	slog.Formatters["mine"] = func() slog.Formatter {
+		return slog.NewJSONFormatter(func(f *slog.JSONFormatter) {
+			f.PrettyPrint = true
+		})
+	}
+	defer delete(slog.Formatters, "mine")
+
+	f, err = slog.NewSwitchFormatterWithConfig(&slog.SwitchFormatterConfig{
+		Default: slog.SwitchFormatterRef{Name: "mine"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, f.Default.(*slog.JSONFormatter).PrettyPrint)
+
+	// the errors
+	for _, s := range []string{
+		`{"default": "not-exist"}`,
+		`{"default": {"name": "json", "template": "{{message}}"}}`,
+		`{"rules": [{"levels": ["unknown"]}]}`,
+		`{"rules": [{"match": "unknown"}]}`,
+		`{"rules": [{"formatter": 1}]}`,
+	} {
+		_, err = slog.LoadSwitchFormatter(strings.NewReader(s))
+		assert.Error(t, err, s)
+	}
+
+	_, err = slog.LoadSwitchFormatterFile("testdata/not-exist.json")
+	assert.Error(t, err)
+}
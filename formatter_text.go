@@ -1,9 +1,13 @@
 package slog
 
 import (
+	"encoding/json"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gookit/color"
 )
@@ -23,24 +27,46 @@ var ColorTheme = map[Level]color.Color{
 }
 
 // TextFormatter definition
+//
+// The template is compiled once on set, supported syntax:
+//
+// - var: {{level}}, {{message}}. the record fields: {{user}}, the nested: {{fields.user.id}}, {{data.ip}}
+// - filters: {{level|upper|pad:7}}, {{datetime|tz:UTC}}, {{caller|trim:30}}, {{data|json}}. see TextFilters
+// - optional section, will be removed on the value is empty: {{?data}} data={{data}}{{/data}}
+//
+// The unknown or invalid tags are output as is. eg: {{ip}} is output as is on the record has not the field "ip",
+// but the missing built-in fields render empty. eg: {{caller}}, {{data.ip}}
 type TextFormatter struct {
-	// Template text template for render output log messages
+	// Template text template for render output log messages.
+	// it is read-only after created, please change it by SetTemplate()
 	Template string
 	// field map, parsed from format string.
 	// eg: {"level": "{{level}}",}
 	fieldMap StringMap
+	// the compiled Template
+	tpl *textTemplate
 
 	// TimeFormat the time format layout. default is time.RFC3339
 	TimeFormat string
-	// Enable color on print log to terminal
+	// Enable color on print log to terminal. the color level is detected once on the first Format
 	EnableColor bool
-	// ColorTheme setting on render color on terminal
+	// ForceColor render the colors on EnableColor, even the output is not a terminal or the env NO_COLOR is set.
+	// the 16 colors are used on the terminal not support color
+	ForceColor bool
+	// ColorTheme setting on render color on terminal. only for the level and message
 	ColorTheme map[Level]color.Color
+	// Theme the colors for each field on EnableColor. eg: slog.DarkTheme.
+	// the ColorTheme is used on it is nil
+	Theme *Theme
 	// FullDisplay Whether to display when record.Data, record.Extra, etc. are empty
 	FullDisplay bool
 	// EncodeFunc data encode for Record.Data, Record.Extra, etc.
 	// Default is encode by `fmt.Sprint()`
 	EncodeFunc func(v interface{}) string
+
+	colorOnce sync.Once
+	// the color level on EnableColor
+	colorLevel ColorLevel
 }
 
 // NewTextFormatter create new TextFormatter
@@ -52,9 +78,7 @@ func NewTextFormatter(template ...string) *TextFormatter {
 		fmtTpl = DefaultTemplate
 	}
 
-	return &TextFormatter{
-		Template: fmtTpl,
-		fieldMap: parseFieldMap(fmtTpl),
+	f := &TextFormatter{
 		// default options
 		TimeFormat: DefaultTimeFormat,
 		ColorTheme: ColorTheme,
@@ -64,12 +88,16 @@ func NewTextFormatter(template ...string) *TextFormatter {
 		// },
 		EncodeFunc: EncodeToString,
 	}
+
+	f.SetTemplate(fmtTpl)
+	return f
 }
 
-// SetTemplate set the log format template and update field-map
+// SetTemplate set the log format template, compile it and update field-map
 func (f *TextFormatter) SetTemplate(fmtTpl string) {
 	f.Template = fmtTpl
-	f.fieldMap = parseFieldMap(fmtTpl)
+	f.tpl = compileTextTemplate(fmtTpl)
+	f.fieldMap = f.tpl.fieldMap
 }
 
 // FieldMap get export field map
@@ -79,102 +107,451 @@ func (f *TextFormatter) FieldMap() StringMap {
 
 // Format an log record
 func (f *TextFormatter) Format(r *Record) ([]byte, error) {
-	oldnew := make([]string, 0, len(f.fieldMap) * 2 + 1)
-	// tplData := make(map[string]string, len(f.fieldMap))
-	for field, tplVar := range f.fieldMap {
-		switch {
-		case field == FieldKeyDatetime:
-			oldnew = append(oldnew, tplVar, r.Time.Format(f.TimeFormat))
-			// tplData[tplVar] = r.Time.Format(f.TimeFormat)
-		case field == FieldKeyTimestamp:
-			// tplData[tplVar] = strconv.Itoa(r.MicroSecond())
-			oldnew = append(oldnew, tplVar, strconv.Itoa(r.MicroSecond()))
-		case field == FieldKeyCaller && r.Caller != nil:
-			// caller eg: "logger_test.go:48,TestLogger_ReportCaller"
-			// tplData[tplVar] = formatCaller(r.Caller, field)
-			oldnew = append(oldnew, tplVar, formatCaller(r.Caller, field))
-		case field == FieldKeyFLine && r.Caller != nil:
-			// "logger_test.go:48"
-			// tplData[tplVar] = formatCaller(r.Caller, field)
-			oldnew = append(oldnew, tplVar, formatCaller(r.Caller, field))
-		case field == FieldKeyFunc && r.Caller != nil:
-			// "github.com/gookit/slog_test.TestLogger_ReportCaller"
-			// tplData[tplVar] = r.Caller.Function
-			oldnew = append(oldnew, tplVar,  r.Caller.Function)
-		case field == FieldKeyFile && r.Caller != nil:
-			// "/work/go/gookit/slog/logger_test.go:48"
-			// tplData[tplVar] = formatCaller(r.Caller, field)
-			oldnew = append(oldnew, tplVar, formatCaller(r.Caller, field))
-		case field == FieldKeyLevel:
-			oldnew = append(oldnew, tplVar)
-			// output colored logs for console
-			if f.EnableColor {
-				// tplData[tplVar] = f.renderColorByLevel(r.LevelName(), r.Level)
-				oldnew = append(oldnew, f.renderColorByLevel(r.LevelName(), r.Level))
-			} else {
-				// tplData[tplVar] = r.LevelName()
-				oldnew = append(oldnew, r.LevelName())
+	tpl := f.tpl
+	// the formatter is not created by NewTextFormatter()
+	if tpl == nil {
+		tpl = compileTextTemplate(f.Template)
+	}
+
+	buf := make([]byte, 0, len(tpl.src)+len(r.Message)+64)
+	return f.render(buf, r, tpl.segments), nil
+}
+
+func (f *TextFormatter) render(buf []byte, r *Record, segments []tplSegment) []byte {
+	for i := range segments {
+		seg := &segments[i]
+		switch seg.kind {
+		case tplText:
+			buf = append(buf, seg.text...)
+		case tplSection:
+			if v, ok := f.fieldValue(r, seg); ok && !isEmptyValue(v) {
+				buf = f.render(buf, r, seg.children)
 			}
-		case field == FieldKeyChannel:
-			// tplData[tplVar] = r.Channel
-			oldnew = append(oldnew, tplVar, r.Channel)
-		case field == FieldKeyMessage:
+		default:
+			v, ok := f.fieldValue(r, seg)
+			if !ok && isUnknownField(r, seg.field) {
+				buf = append(buf, seg.text...)
+				continue
+			}
+
+			for _, fn := range seg.filters {
+				v = fn(f, v)
+			}
+
+			s := f.toString(v)
 			// output colored logs for console
-			oldnew = append(oldnew, tplVar)
 			if f.EnableColor {
-				// tplData[tplVar] = f.renderColorByLevel(r.Message, r.Level)
-				oldnew = append(oldnew, f.renderColorByLevel(r.Message, r.Level))
-			} else {
-				// tplData[tplVar] = r.Message
-				oldnew = append(oldnew, r.Message)
+				s = f.renderColor(seg.field, v, s, r.Level)
+			}
+			buf = append(buf, s...)
+		}
+	}
+	return buf
+}
+
+// get the raw value of the field. returns false on not found
+func (f *TextFormatter) fieldValue(r *Record, seg *tplSegment) (interface{}, bool) {
+	switch seg.field {
+	case FieldKeyDatetime:
+		return r.Time, true
+	case FieldKeyTimestamp:
+		return r.MicroSecond(), true
+	case FieldKeyLevel:
+		return r.LevelName(), true
+	case FieldKeyChannel:
+		return r.Channel, true
+	case FieldKeyMessage:
+		return r.Message, true
+	case FieldKeyCaller, FieldKeyFLine, FieldKeyFile, FieldKeyFcName:
+		if r.Caller == nil {
+			return "", false
+		}
+		// caller eg: "logger_test.go:48,TestLogger_ReportCaller"
+		return formatCaller(r.Caller, seg.field), true
+	case FieldKeyFunc:
+		if r.Caller == nil {
+			return "", false
+		}
+		// "github.com/gookit/slog_test.TestLogger_ReportCaller"
+		return r.Caller.Function, true
+	case FieldKeyData:
+		if len(seg.path) > 0 {
+			return lookupPath(r.Data, seg.path)
+		}
+		if f.FullDisplay || len(r.Data) > 0 {
+			return r.Data, true
+		}
+		return "", false
+	case FieldKeyExtra:
+		if len(seg.path) > 0 {
+			return lookupPath(r.Extra, seg.path)
+		}
+		if f.FullDisplay || len(r.Extra) > 0 {
+			return r.Extra, true
+		}
+		return "", false
+	case "fields":
+		if len(seg.path) > 0 {
+			return lookupPath(r.Fields, seg.path)
+		}
+		if len(r.Fields) > 0 {
+			return r.Fields, true
+		}
+		return "", false
+	}
+
+	if v, ok := r.Fields[seg.field]; ok {
+		if len(seg.path) > 0 {
+			return lookupPath(v, seg.path)
+		}
+		return v, true
+	}
+	return "", false
+}
+
+// check the field is not built-in and not in the record fields
+func isUnknownField(r *Record, field string) bool {
+	switch field {
+	case FieldKeyDatetime, FieldKeyTimestamp, FieldKeyLevel, FieldKeyChannel, FieldKeyMessage,
+		FieldKeyCaller, FieldKeyFLine, FieldKeyFile, FieldKeyFcName, FieldKeyFunc,
+		FieldKeyData, FieldKeyExtra, "fields":
+		return false
+	}
+
+	_, ok := r.Fields[field]
+	return !ok
+}
+
+// convert the value to string for output
+func (f *TextFormatter) toString(v interface{}) string {
+	switch tv := v.(type) {
+	case string:
+		return tv
+	case time.Time:
+		return tv.Format(f.TimeFormat)
+	}
+
+	if f.EncodeFunc != nil {
+		return f.EncodeFunc(v)
+	}
+	return EncodeToString(v)
+}
+
+func (f *TextFormatter) renderColor(field string, val interface{}, s string, level Level) string {
+	f.colorOnce.Do(func() {
+		f.colorLevel = resolveColorLevel(f.ForceColor)
+	})
+
+	if f.colorLevel == ColorLevelNone {
+		return s
+	}
+	if f.Theme != nil {
+		return f.Theme.renderField(field, val, s, level, f.colorLevel)
+	}
+
+	if field == FieldKeyLevel || field == FieldKeyMessage {
+		return f.renderColorByLevel(s, level)
+	}
+	return s
+}
+
+func (f *TextFormatter) renderColorByLevel(text string, level Level) string {
+	if theme, ok := f.ColorTheme[level]; ok {
+		return renderColorCode(theme.String(), text)
+	}
+
+	return text
+}
+
+// TextFilter the filter for the TextFormatter template, receive the value and return new value.
+type TextFilter func(f *TextFormatter, v interface{}) interface{}
+
+// TextFilters the filters for the TextFormatter template, create the filter by the argument.
+// the argument is the text after ':', returns nil on the argument is invalid.
+//
+// Built-in:
+//
+// - upper, lower: convert the case
+// - pad:N pad the spaces to N chars, left aligned. pad:-N is right aligned
+// - trim:N truncate to N chars. trim:-N keep the last N chars
+// - tz:NAME convert the time to the time zone. eg: tz:UTC, tz:Asia/Shanghai
+// - format:LAYOUT format the time by the layout. eg: format:15:04:05.000
+// - json: encode the value as JSON
+//
+// Usage:
+// 	slog.TextFilters["quote"] = func(arg string) slog.TextFilter {
+// 		return func(f *slog.TextFormatter, v interface{}) interface{} {
+// 			return strconv.Quote(f.EncodeFunc(v))
+// 		}
+// 	}
+var TextFilters = map[string]func(arg string) TextFilter{
+	"upper": func(string) TextFilter {
+		return func(f *TextFormatter, v interface{}) interface{} {
+			return strings.ToUpper(f.toString(v))
+		}
+	},
+	"lower": func(string) TextFilter {
+		return func(f *TextFormatter, v interface{}) interface{} {
+			return strings.ToLower(f.toString(v))
+		}
+	},
+	"pad": func(arg string) TextFilter {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil
+		}
+
+		return func(f *TextFormatter, v interface{}) interface{} {
+			s := f.toString(v)
+			if n >= 0 {
+				if pad := n - utf8.RuneCountInString(s); pad > 0 {
+					return s + strings.Repeat(" ", pad)
+				}
+			} else if pad := -n - utf8.RuneCountInString(s); pad > 0 {
+				return strings.Repeat(" ", pad) + s
+			}
+			return s
+		}
+	},
+	"trim": func(arg string) TextFilter {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil
+		}
+
+		return func(f *TextFormatter, v interface{}) interface{} {
+			s := f.toString(v)
+			if n >= 0 {
+				if utf8.RuneCountInString(s) > n {
+					return string([]rune(s)[:n])
+				}
+			} else if rs := []rune(s); len(rs) > -n {
+				return string(rs[len(rs)+n:])
 			}
-		case field == FieldKeyData:
-			if f.FullDisplay || len(r.Data) > 0 {
-				// tplData[tplVar] = f.EncodeFunc(r.Data)
-				oldnew = append(oldnew, tplVar, f.EncodeFunc(r.Data))
-			} else {
-				// tplData[tplVar] = ""
-				oldnew = append(oldnew, tplVar, "")
+			return s
+		}
+	},
+	"tz": func(arg string) TextFilter {
+		loc, err := time.LoadLocation(arg)
+		if err != nil {
+			return nil
+		}
+
+		return func(f *TextFormatter, v interface{}) interface{} {
+			if t, ok := v.(time.Time); ok {
+				return t.In(loc)
+			}
+			return v
+		}
+	},
+	"format": func(layout string) TextFilter {
+		if layout == "" {
+			return nil
+		}
+
+		return func(f *TextFormatter, v interface{}) interface{} {
+			if t, ok := v.(time.Time); ok {
+				return t.Format(layout)
+			}
+			return v
+		}
+	},
+	"json": func(string) TextFilter {
+		return func(f *TextFormatter, v interface{}) interface{} {
+			bs, err := json.Marshal(v)
+			if err != nil {
+				return f.toString(v)
+			}
+			return string(bs)
+		}
+	},
+}
+
+// the kinds of the template segment
+const (
+	tplText uint8 = iota
+	tplVar
+	tplSection
+)
+
+type tplSegment struct {
+	kind uint8
+	// the literal text. it is the tag text for the var
+	text string
+	// the field name and nested keys. eg: {{fields.user.id}} -> "fields", ["user", "id"]
+	field string
+	path  []string
+	// the filters of the var
+	filters []TextFilter
+	// the segments in the section
+	children []tplSegment
+}
+
+// the compiled template
+type textTemplate struct {
+	src      string
+	segments []tplSegment
+	fieldMap StringMap
+}
+
+var (
+	tplTagRegex  = regexp.MustCompile(`{{([^{}]+)}}`)
+	tplNameRegex = regexp.MustCompile(`^\w+(\.\w+)*$`)
+)
+
+// compile the template string to the segment list
+func compileTextTemplate(src string) *textTemplate {
+	tpl := &textTemplate{src: src, fieldMap: make(StringMap)}
+
+	type section struct {
+		name string
+		seg  tplSegment
+		// the segments before the section
+		parent []tplSegment
+	}
+
+	var segments []tplSegment
+	var stack []section
+
+	pushText := func(s string) {
+		if s == "" {
+			return
+		}
+		if n := len(segments); n > 0 && segments[n-1].kind == tplText {
+			segments[n-1].text += s
+			return
+		}
+		segments = append(segments, tplSegment{kind: tplText, text: s})
+	}
+
+	last := 0
+	for _, loc := range tplTagRegex.FindAllStringSubmatchIndex(src, -1) {
+		pushText(src[last:loc[0]])
+		last = loc[1]
+
+		tag, expr := src[loc[0]:loc[1]], strings.TrimSpace(src[loc[2]:loc[3]])
+		// the empty tag. eg: {{ }}
+		if expr == "" {
+			pushText(tag)
+			continue
+		}
+
+		switch expr[0] {
+		case '?': // section start. eg: {{?data}}
+			name := strings.TrimSpace(expr[1:])
+			seg, ok := parseTplVar(name)
+			if !ok {
+				pushText(tag)
+				continue
 			}
-		case field == FieldKeyExtra:
-			if f.FullDisplay || len(r.Extra) > 0 {
-				// tplData[tplVar] = f.EncodeFunc(r.Extra)
-				oldnew = append(oldnew, tplVar, f.EncodeFunc(r.Extra))
-			} else {
-				// tplData[tplVar] = ""
-				oldnew = append(oldnew, tplVar, "")
+
+			seg.kind = tplSection
+			tpl.fieldMap[seg.field] = tag
+			stack = append(stack, section{name: name, seg: seg, parent: segments})
+			segments = nil
+		case '/': // section end. eg: {{/data}}
+			if n := len(stack); n > 0 && stack[n-1].name == strings.TrimSpace(expr[1:]) {
+				sec := stack[n-1]
+				stack = stack[:n-1]
+
+				sec.seg.children = segments
+				segments = append(sec.parent, sec.seg)
+				continue
 			}
+			pushText(tag)
 		default:
-			if _, ok := r.Fields[field]; ok {
-				// tplData[tplVar] = f.EncodeFunc(r.Fields[field])
-				oldnew = append(oldnew, tplVar, f.EncodeFunc(r.Fields[field]))
+			nodes := strings.Split(expr, "|")
+			seg, ok := parseTplVar(strings.TrimSpace(nodes[0]))
+			for _, node := range nodes[1:] {
+				if !ok {
+					break
+				}
+
+				name, arg := strings.TrimSpace(node), ""
+				if pos := strings.IndexByte(name, ':'); pos > 0 {
+					name, arg = name[:pos], name[pos+1:]
+				}
+
+				var fn TextFilter
+				if newFn, has := TextFilters[name]; has {
+					fn = newFn(arg)
+				}
+
+				ok = fn != nil
+				seg.filters = append(seg.filters, fn)
 			}
+
+			if !ok {
+				pushText(tag)
+				continue
+			}
+
+			if _, has := tpl.fieldMap[seg.field]; !has {
+				tpl.fieldMap[seg.field] = tag
+			}
+			// output as is on the field is unknown
+			seg.text = tag
+			segments = append(segments, seg)
 		}
 	}
+	pushText(src[last:])
 
-	// str := strutil.Replaces(f.Template, tplData)
-	str := strings.NewReplacer(oldnew...).Replace(f.Template)
-	return []byte(str), nil
+	// the unclosed sections are closed at the end
+	for n := len(stack) - 1; n >= 0; n-- {
+		stack[n].seg.children = segments
+		segments = append(stack[n].parent, stack[n].seg)
+	}
+
+	tpl.segments = segments
+	return tpl
 }
 
-func (f *TextFormatter) renderColorByLevel(text string, level Level) string {
-	if theme, ok := f.ColorTheme[level]; ok {
-		return theme.Render(text)
+// parse the var name. eg: "fields.user.id"
+func parseTplVar(name string) (tplSegment, bool) {
+	if !tplNameRegex.MatchString(name) {
+		return tplSegment{}, false
 	}
 
-	return text
+	nodes := strings.Split(name, ".")
+	seg := tplSegment{kind: tplVar, field: nodes[0]}
+	if len(nodes) > 1 {
+		seg.path = nodes[1:]
+	}
+	return seg, true
 }
 
-// parse string "{{channel}}" to map { "channel": "{{channel}}" }
-func parseFieldMap(format string) StringMap {
-	rgp := regexp.MustCompile(`{{\w+}}`)
+// lookup the value by the nested keys
+func lookupPath(v interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		var ok bool
+		switch mp := v.(type) {
+		case M:
+			v, ok = mp[key]
+		case map[string]interface{}:
+			v, ok = mp[key]
+		case map[string]string:
+			v, ok = mp[key]
+		}
 
-	ss := rgp.FindAllString(format, -1)
-	fm := make(StringMap)
-	for _, tplVar := range ss {
-		field := strings.Trim(tplVar, "{}")
-		fm[field] = tplVar
+		if !ok {
+			return "", false
+		}
 	}
+	return v, true
+}
 
-	return fm
+func isEmptyValue(v interface{}) bool {
+	switch tv := v.(type) {
+	case nil:
+		return true
+	case string:
+		return tv == ""
+	case M:
+		return len(tv) == 0
+	case map[string]interface{}:
+		return len(tv) == 0
+	case []interface{}:
+		return len(tv) == 0
+	}
+	return false
 }
@@ -9,6 +9,7 @@ import (
 // bufferWrapper struct
 type bufferWrapper struct {
 	lockWrapper
+	slog.HandlerMetrics
 	buffer  *bufio.Writer
 	handler slog.FormatterWriterHandler
 }
@@ -61,6 +62,7 @@ func (w *bufferWrapper) Handle(record *slog.Record) error {
 	// 	w.buffer = bufio.NewWriterSize(w.handler.Writer(), w.buffSize)
 	// }
 
-	_, err = w.buffer.Write(bts)
+	n, err := w.buffer.Write(bts)
+	w.AddWritten(n)
 	return err
 }
@@ -11,6 +11,7 @@ import (
 type BufferedHandler struct {
 	lockWrapper
 	LevelsWithFormatter
+	slog.HandlerMetrics
 
 	buffer  *bufio.Writer
 	cWriter io.WriteCloser
@@ -62,6 +63,7 @@ func (h *BufferedHandler) Handle(record *slog.Record) error {
 	// 	h.buffer = bufio.NewWriterSize(h.fcWriter.Writer(), h.BuffSize)
 	// }
 
-	_, err = h.buffer.Write(bts)
+	n, err := h.buffer.Write(bts)
+	h.AddWritten(n)
 	return err
 }
@@ -0,0 +1,319 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tomorrowsky/slog"
+)
+
+// DefaultIndexTemplate default index name template for the ElasticsearchHandler
+var DefaultIndexTemplate = "logs-{channel}-{date:2006.01.02}"
+
+// esItem an bulk item
+type esItem struct {
+	index string
+	doc   []byte
+}
+
+// esBulkResponse the response of the bulk API
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// ElasticsearchHandler definition. index records to Elasticsearch/OpenSearch by the _bulk API.
+//
+// - the index name is rendered by the IndexTemplate, with the record time and channel
+// - the document is formatted by the formatter. default is slog.JSONFormatter with slog.ECSAliases
+// - records are batched by the BatchSize, BatchBytes, and sent every FlushInterval
+// - the failed items with status 429 or 5xx will be retried, others will be dropped
+type ElasticsearchHandler struct {
+	lockWrapper
+	LevelsWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	pending      []esItem
+	pendingBytes int
+
+	flusher *intervalFlusher
+	closed  bool
+
+	// URL the server url. eg: "http://localhost:9200"
+	URL string
+	// IndexTemplate the index name template. default is DefaultIndexTemplate
+	//
+	// - "{channel}" will be replaced to the record channel
+	// - "{date:LAYOUT}" will be replaced to the record time formatted by the LAYOUT. see time.Time.Format()
+	// - "{date}" is same as "{date:2006.01.02}"
+	// - the other text is kept as is
+	//
+	// NOTICE: the index name will be converted to lowercase.
+	IndexTemplate string
+	// OpType the bulk action. default is "index", use "create" for the data streams
+	OpType string
+	// Username and Password for the basic auth
+	Username string
+	Password string
+	// APIKey for the API key auth
+	APIKey string
+	// Headers for the request
+	Headers map[string]string
+	// Client for send request. default timeout is 10s
+	Client *http.Client
+	// BatchSize max records of an request. default is 500
+	BatchSize int
+	// BatchBytes max body size of an request. default is 5MB
+	BatchBytes int
+	// FlushInterval for send the batched records, 0 to disable the timed flush. default is 1s
+	FlushInterval time.Duration
+	// MaxRetries for resend the request or the failed items. default is 3
+	MaxRetries int
+	// Backoff settings for wait before resend
+	Backoff Backoff
+}
+
+// NewElasticsearch create new ElasticsearchHandler. see NewElasticsearchHandler()
+func NewElasticsearch(url string, levels []slog.Level, fns ...func(h *ElasticsearchHandler)) *ElasticsearchHandler {
+	return NewElasticsearchHandler(url, levels, fns...)
+}
+
+// NewElasticsearchHandler create new ElasticsearchHandler, and start the flush daemon.
+//
+// Usage:
+// 	h := handler.NewElasticsearchHandler("http://localhost:9200", slog.AllLevels, func(h *handler.ElasticsearchHandler) {
+// 		h.IndexTemplate = "myapp-{channel}-{date:2006.01}"
+// 		h.APIKey = "..."
+// 	})
+// 	defer h.Close()
+func NewElasticsearchHandler(url string, levels []slog.Level, fns ...func(h *ElasticsearchHandler)) *ElasticsearchHandler {
+	h := &ElasticsearchHandler{
+		URL: strings.TrimRight(url, "/"),
+		// init levels
+		LevelsWithFormatter: newLvsFormatter(levels),
+		// default options
+		IndexTemplate: DefaultIndexTemplate,
+		OpType:        "index",
+		Client:        &http.Client{Timeout: 10 * time.Second},
+		BatchSize:     500,
+		BatchBytes:    5 * 1024 * 1024,
+		FlushInterval: time.Second,
+		MaxRetries:    3,
+		Backoff:       DefaultBackoff,
+	}
+
+	h.SetFormatter(slog.NewJSONFormatter(func(f *slog.JSONFormatter) {
+		f.Aliases = slog.ECSAliases
+		f.TimeFormat = time.RFC3339Nano
+	}))
+
+	for _, fn := range fns {
+		fn(h)
+	}
+
+	h.flusher = startIntervalFlusher(h.FlushInterval, func() {
+		_ = h.Flush()
+	})
+	return h
+}
+
+// IndexName render the index name for the record
+func (h *ElasticsearchHandler) IndexName(r *slog.Record) string {
+	ts := r.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	// the invalid chars for the index name
+	channel := strings.Map(func(c rune) rune {
+		if strings.ContainsRune(`\/*?"<>| ,#:`, c) {
+			return '_'
+		}
+		return c
+	}, r.Channel)
+
+	var sb strings.Builder
+	tpl := h.IndexTemplate
+	for {
+		start := strings.IndexByte(tpl, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(tpl[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		sb.WriteString(tpl[:start])
+		switch key := tpl[start+1 : end]; {
+		case key == "channel":
+			sb.WriteString(channel)
+		case key == "date":
+			sb.WriteString(ts.Format("2006.01.02"))
+		case strings.HasPrefix(key, "date:"):
+			sb.WriteString(ts.Format(key[5:]))
+		default: // keep the unknown placeholder
+			sb.WriteString(tpl[start : end+1])
+		}
+		tpl = tpl[end+1:]
+	}
+	sb.WriteString(tpl)
+
+	return strings.ToLower(sb.String())
+}
+
+// Handle log record
+func (h *ElasticsearchHandler) Handle(r *slog.Record) error {
+	index := h.IndexName(r)
+	doc, err := h.Formatter().Format(r)
+	if err != nil {
+		return err
+	}
+
+	// copy it, the buffer may be reused
+	item := esItem{index: index, doc: bytes.TrimRight(append([]byte(nil), doc...), "\n")}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.closed {
+		return ErrHandlerClosed
+	}
+
+	h.pending = append(h.pending, item)
+	h.pendingBytes += len(item.doc)
+	if len(h.pending) >= h.BatchSize || h.pendingBytes >= h.BatchBytes {
+		return h.flush()
+	}
+	return nil
+}
+
+func (h *ElasticsearchHandler) flush() error {
+	items := h.pending
+	h.pending = nil
+	h.pendingBytes = 0
+
+	var firstErr error
+	for attempt := 0; len(items) > 0; attempt++ {
+		if attempt > 0 {
+			time.Sleep(h.Backoff.Duration(attempt - 1))
+		}
+
+		retries, retryErr, err := h.bulk(items)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+
+		if len(retries) == 0 {
+			break
+		}
+
+		if attempt >= h.MaxRetries {
+			h.AddDropped(len(retries))
+			if firstErr == nil {
+				firstErr = retryErr
+			}
+			break
+		}
+		items = retries
+	}
+	return firstErr
+}
+
+// send the items by the _bulk API.
+// return the items need retry and the error of them, and the error of the dropped items.
+func (h *ElasticsearchHandler) bulk(items []esItem) (retries []esItem, retryErr, err error) {
+	var buf bytes.Buffer
+	for _, item := range items {
+		meta, _ := json.Marshal(map[string]map[string]string{h.OpType: {"_index": item.index}})
+		buf.Write(meta)
+		buf.WriteByte('\n')
+		buf.Write(item.doc)
+		buf.WriteByte('\n')
+	}
+
+	body := buf.Bytes()
+	respBody, err := doHTTPWithRetry(h.Client, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, h.URL+"/_bulk", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		if h.Username != "" {
+			req.SetBasicAuth(h.Username, h.Password)
+		}
+		if h.APIKey != "" {
+			req.Header.Set("Authorization", "ApiKey "+h.APIKey)
+		}
+		for k, v := range h.Headers {
+			req.Header.Set(k, v)
+		}
+		return req, nil
+	}, h.MaxRetries, h.Backoff)
+
+	if err != nil {
+		// the request is failed, no need retry again
+		h.AddDropped(len(items))
+		return nil, nil, err
+	}
+
+	h.AddWritten(len(body))
+
+	var resp esBulkResponse
+	if err = json.Unmarshal(respBody, &resp); err != nil || !resp.Errors {
+		return nil, nil, err
+	}
+
+	// check the per-item errors
+	for i, result := range resp.Items {
+		if i >= len(items) {
+			break
+		}
+
+		for _, st := range result {
+			if st.Status < 300 {
+				continue
+			}
+
+			itemErr := &HTTPError{StatusCode: st.Status, Body: string(st.Error)}
+			if itemErr.Retryable() {
+				retries = append(retries, items[i])
+				retryErr = itemErr
+			} else {
+				h.AddDropped(1)
+				err = itemErr
+			}
+		}
+	}
+	return retries, retryErr, err
+}
+
+// Flush send the pending records
+func (h *ElasticsearchHandler) Flush() error {
+	h.Lock()
+	defer h.Unlock()
+	return h.flush()
+}
+
+// Close stop the flush daemon, and send the pending records
+func (h *ElasticsearchHandler) Close() error {
+	h.Lock()
+	if h.closed {
+		h.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.Unlock()
+
+	h.flusher.Stop()
+	return h.Flush()
+}
@@ -0,0 +1,96 @@
+package handler_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+func TestElasticsearchHandler_IndexName(t *testing.T) {
+	h := handler.NewElasticsearchHandler("http://localhost:9200", slog.AllLevels)
+	defer h.Close()
+
+	r := &slog.Record{Time: time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC), Channel: "My App/Web"}
+	assert.Equal(t, "logs-my_app_web-2021.03.01", h.IndexName(r))
+
+	// the literal text is kept
+	h.IndexTemplate = "logs-v2-Jan-{channel}-{date:2006.01}-{date}-{other}"
+	assert.Equal(t, "logs-v2-jan-my_app_web-2021.03-2021.03.01-{other}", h.IndexName(r))
+	h.IndexTemplate = "logs-v2-2006.01.02-{date"
+	assert.Equal(t, "logs-v2-2006.01.02-{date", h.IndexName(r))
+}
+
+func TestElasticsearchHandler(t *testing.T) {
+	var mu sync.Mutex
+	var bulks [][]map[string]interface{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_bulk", r.URL.Path)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		assert.Equal(t, "ApiKey secret", r.Header.Get("Authorization"))
+
+		body, _ := ioutil.ReadAll(r.Body)
+		var lines []map[string]interface{}
+		sc := bufio.NewScanner(bytes.NewReader(body))
+		for sc.Scan() {
+			line := map[string]interface{}{}
+			assert.NoError(t, json.Unmarshal(sc.Bytes(), &line))
+			lines = append(lines, line)
+		}
+
+		mu.Lock()
+		bulks = append(bulks, lines)
+		first := len(bulks) == 1
+		mu.Unlock()
+
+		// the first request: item 2 is rejected(429), item 3 is invalid(400)
+		if first {
+			_, _ = w.Write([]byte(`{"errors":true,"items":[{"index":{"status":201}},` +
+				`{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}},` +
+				`{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer srv.Close()
+
+	h := handler.NewElasticsearchHandler(srv.URL, slog.AllLevels, func(h *handler.ElasticsearchHandler) {
+		h.APIKey = "secret"
+		h.BatchSize = 3
+		h.FlushInterval = time.Hour
+		h.Backoff = fastBackoff
+	})
+	defer h.Close()
+
+	tm := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, h.Handle(&slog.Record{Time: tm, Level: slog.InfoLevel, Channel: "web", Message: "message 1"}))
+	assert.NoError(t, h.Handle(&slog.Record{Time: tm, Level: slog.InfoLevel, Channel: "web", Message: "message 2"}))
+	assert.Error(t, h.Handle(&slog.Record{Time: tm, Level: slog.InfoLevel, Channel: "db", Message: "message 3"}))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Len(t, bulks, 2)
+	assert.Len(t, bulks[0], 6)
+	assert.Equal(t, map[string]interface{}{"index": map[string]interface{}{"_index": "logs-web-2021.03.01"}}, bulks[0][0])
+	assert.Equal(t, "message 1", bulks[0][1]["message"])
+	assert.Equal(t, "INFO", bulks[0][1]["log.level"])
+	assert.Equal(t, "web", bulks[0][1]["log.logger"])
+	assert.Equal(t, "2021-03-01T12:00:00Z", bulks[0][1]["@timestamp"])
+	assert.Equal(t, map[string]interface{}{"index": map[string]interface{}{"_index": "logs-db-2021.03.01"}}, bulks[0][4])
+
+	// only the rejected item is retried
+	assert.Len(t, bulks[1], 2)
+	assert.Equal(t, "message 2", bulks[1][1]["message"])
+	assert.Equal(t, uint64(1), h.Dropped())
+}
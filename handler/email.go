@@ -1,11 +1,42 @@
 package handler
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"html"
+	"mime"
+	"mime/quotedprintable"
+	"net"
 	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/tomorrowsky/slog"
 )
 
+// the TLS modes for the EmailHandler
+const (
+	// EmailTLSAuto use the STARTTLS if the server supports it
+	EmailTLSAuto uint8 = iota
+	// EmailTLSStartTLS require the STARTTLS
+	EmailTLSStartTLS
+	// EmailTLSImplicit connect to the server with TLS. eg: port 465
+	EmailTLSImplicit
+	// EmailTLSNone disable the TLS
+	EmailTLSNone
+)
+
+// DefaultEmailSubject default subject template for the EmailHandler
+var DefaultEmailSubject = "[{{level}}] {{channel}}: {{message}}"
+
+// ErrEmailNoStartTLS returns on the STARTTLS is required, but the server not support it
+var ErrEmailNoStartTLS = errors.New("slog: smtp server does not support STARTTLS")
+
 // EmailOption struct
 type EmailOption struct {
 	SmtpHost string // eg "smtp.gmail.com"
@@ -14,39 +45,319 @@ type EmailOption struct {
 	Password string
 }
 
-// EmailHandler struct
+// EmailHandler struct. send records as email by SMTP.
+//
+// - the records are grouped into an digest email in the Window, or on the MaxRecords is reached
+// - the subject is rendered by the Subject template with the highest level record
+// - the email is an RFC 5322 message with plain text and HTML bodies(multipart/alternative)
 type EmailHandler struct {
-	// NopFlushClose provide empty Flush(), Close() methods
-	NopFlushClose
+	lockWrapper
 	// LevelWithFormatter support level and formatter
 	LevelWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	records []*slog.Record
+	texts   [][]byte
+
+	flusher *intervalFlusher
+	closed  bool
+
 	// From the sender email information
 	From EmailOption
 	// ToAddresses list
 	ToAddresses []string
+	// Subject template, the var syntax is same as slog.TextFormatter. default is DefaultEmailSubject
+	Subject string
+	// HTML add an HTML body. default is true
+	HTML bool
+	// TLSMode for connect the server. default is EmailTLSAuto
+	TLSMode uint8
+	// TLSConfig for the TLS connection. default will verify the SmtpHost
+	TLSConfig *tls.Config
+	// Window for group the records into an digest email, 0 to send each record immediately. default is 1 minute
+	Window time.Duration
+	// MaxRecords max records of an email, will send immediately on reached. default is 100
+	MaxRecords int
+	// Timeout for connect and send an email. default is 10s
+	Timeout time.Duration
 }
 
-// NewEmailHandler instance
-func NewEmailHandler(from EmailOption, toAddresses []string) *EmailHandler {
+// NewEmailHandler instance, and start the flush daemon.
+//
+// Usage:
+// 	h := handler.NewEmailHandler(handler.EmailOption{
+// 		SmtpHost: "smtp.gmail.com",
+// 		SmtpPort: "587",
+// 		FromAddr: "yourEmail@gmail.com",
+// 		Password: "...",
+// 	}, []string{"oncall@example.com"}, func(h *handler.EmailHandler) {
+// 		h.Level = slog.ErrorLevel
+// 		h.Window = 5 * time.Minute
+// 	})
+// 	defer h.Close()
+func NewEmailHandler(from EmailOption, toAddresses []string, fns ...func(h *EmailHandler)) *EmailHandler {
 	h := &EmailHandler{
 		From: from,
 		// to receivers
 		ToAddresses: toAddresses,
+		// default options
+		Subject:    DefaultEmailSubject,
+		HTML:       true,
+		Window:     time.Minute,
+		MaxRecords: 100,
+		Timeout:    10 * time.Second,
 	}
 
 	// init default log level
 	h.Level = slog.InfoLevel
+	for _, fn := range fns {
+		fn(h)
+	}
+
+	h.flusher = startIntervalFlusher(h.Window, func() {
+		_ = h.Flush()
+	})
 	return h
 }
 
 // Handle an log record
 func (h *EmailHandler) Handle(r *slog.Record) error {
-	msgBytes, err := h.FormatRecord(r)
+	text, err := h.FormatRecord(r)
+	if err != nil {
+		return err
+	}
 
-	var auth = smtp.PlainAuth("", h.From.FromAddr, h.From.Password, h.From.SmtpHost)
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.closed {
+		return ErrHandlerClosed
+	}
+
+	// the record and buffer may be reused after Handle() returns
+	h.records = append(h.records, r.Snapshot())
+	h.texts = append(h.texts, append([]byte(nil), text...))
+
+	if h.Window <= 0 || len(h.records) >= h.MaxRecords {
+		return h.flush()
+	}
+	return nil
+}
+
+func (h *EmailHandler) flush() error {
+	if len(h.records) == 0 {
+		return nil
+	}
 
-	addr := h.From.SmtpHost + ":" + h.From.SmtpPort
-	err = smtp.SendMail(addr, auth, h.From.FromAddr, h.ToAddresses, msgBytes)
+	n := len(h.records)
+	msg, err := h.buildMessage(h.records, h.texts)
+	h.records, h.texts = nil, nil
+	if err == nil {
+		err = h.send(msg)
+	}
+
+	if err != nil {
+		h.AddDropped(n)
+		return err
+	}
+
+	h.AddWritten(len(msg))
+	return nil
+}
+
+// render the subject by the highest level record
+func (h *EmailHandler) subject(records []*slog.Record) (string, error) {
+	top := records[0]
+	for _, r := range records[1:] {
+		if r.Level < top.Level {
+			top = r
+		}
+	}
+
+	bs, err := slog.NewTextFormatter(h.Subject).Format(top)
+	if err != nil {
+		return "", err
+	}
+
+	// the header value must be single line
+	subject := strings.Join(strings.Fields(string(bs)), " ")
+	if len(records) > 1 {
+		subject += " (+" + strconv.Itoa(len(records)-1) + " more)"
+	}
+	return subject, nil
+}
+
+// build the RFC 5322 message
+func (h *EmailHandler) buildMessage(records []*slog.Record, texts [][]byte) ([]byte, error) {
+	subject, err := h.subject(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+
+	writeHeader("From", h.From.FromAddr)
+	writeHeader("To", strings.Join(h.ToAddresses, ", "))
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("Message-ID", "<"+randomHex(16)+"@"+emailDomain(h.From.FromAddr)+">")
+	writeHeader("MIME-Version", "1.0")
+
+	plain := bytes.Join(texts, nil)
+	if !h.HTML {
+		writeHeader("Content-Type", "text/plain; charset=utf-8")
+		writeHeader("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		writeQuotedPrintable(&buf, plain)
+		return buf.Bytes(), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<html><body>\n")
+	for _, text := range texts {
+		sb.WriteString(`<pre style="margin:0 0 4px 0">` + html.EscapeString(strings.TrimRight(string(text), "\n")) + "</pre>\n")
+	}
+	sb.WriteString("</body></html>\n")
+
+	boundary := "slog-" + randomHex(12)
+	writeHeader("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct {
+		typ  string
+		body []byte
+	}{
+		{"text/plain", plain},
+		{"text/html", []byte(sb.String())},
+	} {
+		buf.WriteString("--" + boundary + "\r\n")
+		writeHeader("Content-Type", part.typ+"; charset=utf-8")
+		writeHeader("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		writeQuotedPrintable(&buf, part.body)
+		buf.WriteString("\r\n")
+	}
+
+	buf.WriteString("--" + boundary + "--\r\n")
+	return buf.Bytes(), nil
+}
+
+// send the message by an new SMTP connection
+func (h *EmailHandler) send(msg []byte) error {
+	addr := net.JoinHostPort(h.From.SmtpHost, h.From.SmtpPort)
+	tlsConfig := h.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: h.From.SmtpHost}
+	}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: h.Timeout}
+	if h.TLSMode == EmailTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(h.Timeout))
+	c, err := smtp.NewClient(conn, h.From.SmtpHost)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if h.TLSMode == EmailTLSAuto || h.TLSMode == EmailTLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err = c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		} else if h.TLSMode == EmailTLSStartTLS {
+			return ErrEmailNoStartTLS
+		}
+	}
+
+	if h.From.Password != "" {
+		if ok, _ := c.Extension("AUTH"); ok {
+			auth := smtp.PlainAuth("", h.From.FromAddr, h.From.Password, h.From.SmtpHost)
+			if err = c.Auth(auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = c.Mail(h.From.FromAddr); err != nil {
+		return err
+	}
+	for _, addr := range h.ToAddresses {
+		if err = c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Flush send the pending records as an digest email
+func (h *EmailHandler) Flush() error {
+	h.Lock()
+	defer h.Unlock()
+	return h.flush()
+}
+
+// Close stop the flush daemon, and send the pending records
+func (h *EmailHandler) Close() error {
+	h.Lock()
+	if h.closed {
+		h.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.Unlock()
+
+	h.flusher.Stop()
+	return h.Flush()
+}
+
+func writeQuotedPrintable(buf *bytes.Buffer, body []byte) {
+	qw := quotedprintable.NewWriter(buf)
+	_, _ = qw.Write(body)
+	_ = qw.Close()
+}
+
+// get the domain of an email address. eg: "example.com"
+func emailDomain(addr string) string {
+	if i := strings.LastIndexByte(addr, '@'); i >= 0 {
+		return strings.Trim(addr[i+1:], "> ")
+	}
+
+	host, _ := os.Hostname()
+	return host
+}
 
-	return err
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
 }
@@ -0,0 +1,277 @@
+package handler_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+// fakeSMTPServer an in-process SMTP server, support STARTTLS and AUTH PLAIN.
+type fakeSMTPServer struct {
+	ln       net.Listener
+	tlsConf  *tls.Config
+	mu       sync.Mutex
+	mails    []string
+	rcpts    []string
+	auth     string
+	startTLS bool
+}
+
+func newFakeSMTPServer(t *testing.T, implicitTLS bool) *fakeSMTPServer {
+	srv := httptest.NewTLSServer(nil)
+	cert := srv.TLS.Certificates[0]
+	srv.Close()
+
+	s := &fakeSMTPServer{tlsConf: &tls.Config{Certificates: []tls.Certificate{cert}}}
+
+	var err error
+	if implicitTLS {
+		s.ln, err = tls.Listen("tcp", "127.0.0.1:0", s.tlsConf)
+	} else {
+		s.ln, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	assert.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := s.ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost ESMTP")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO":
+			reply("250-localhost")
+			_, isTLS := conn.(*tls.Conn)
+			if !isTLS {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tc := tls.Server(conn, s.tlsConf)
+			if tc.Handshake() != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.startTLS = true
+			s.mu.Unlock()
+			conn, r = tc, bufio.NewReader(tc)
+		case "AUTH":
+			s.mu.Lock()
+			s.auth = line
+			s.mu.Unlock()
+			reply("235 OK")
+		case "MAIL":
+			reply("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, line)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+
+			var sb strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				sb.WriteString(strings.TrimPrefix(line, "."))
+			}
+
+			s.mu.Lock()
+			s.mails = append(s.mails, sb.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) Mails() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.mails...)
+}
+
+func (s *fakeSMTPServer) Option() handler.EmailOption {
+	_, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return handler.EmailOption{
+		SmtpHost: "127.0.0.1",
+		SmtpPort: port,
+		FromAddr: "app@example.com",
+		Password: "secret",
+	}
+}
+
+func TestEmailHandler(t *testing.T) {
+	s := newFakeSMTPServer(t, false)
+	defer s.ln.Close()
+
+	h := handler.NewEmailHandler(s.Option(), []string{"a@example.com", "b@example.com"}, func(h *handler.EmailHandler) {
+		h.Level = slog.WarnLevel
+		h.Window = time.Hour
+		h.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+		h.SetFormatter(slog.NewTextFormatter("[{{level}}] {{message}}\n"))
+	})
+
+	l := slog.NewWithHandlers(h)
+	l.ReportCaller = false
+	l.Info("info message")
+	l.Warn("disk is full")
+	l.Error("db <primary> is down")
+	assert.Len(t, s.Mails(), 0)
+
+	// the records are sent in an digest email
+	assert.NoError(t, h.Flush())
+	mails := s.Mails()
+	assert.Len(t, mails, 1)
+
+	s.mu.Lock()
+	assert.True(t, s.startTLS)
+	assert.Contains(t, s.auth, "AUTH PLAIN")
+	assert.Equal(t, []string{"RCPT TO:<a@example.com>", "RCPT TO:<b@example.com>"}, s.rcpts)
+	s.mu.Unlock()
+
+	msg, err := mail.ReadMessage(strings.NewReader(mails[0]))
+	assert.NoError(t, err)
+	assert.Equal(t, "app@example.com", msg.Header.Get("From"))
+	assert.Equal(t, "a@example.com, b@example.com", msg.Header.Get("To"))
+	assert.Equal(t, "[ERROR] application: db <primary> is down (+1 more)", msg.Header.Get("Subject"))
+	assert.NotEmpty(t, msg.Header.Get("Message-ID"))
+	_, err = msg.Header.Date()
+	assert.NoError(t, err)
+
+	// the plain and html bodies
+	mt, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mt)
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	part, err := mr.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", part.Header.Get("Content-Type"))
+	body, _ := ioutil.ReadAll(part)
+	assert.Equal(t, "[WARNING] disk is full\r\n[ERROR] db <primary> is down\r\n", string(body))
+
+	part, err = mr.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", part.Header.Get("Content-Type"))
+	body, _ = ioutil.ReadAll(part)
+	assert.Contains(t, string(body), "db &lt;primary&gt; is down")
+
+	assert.NoError(t, h.Close())
+	assert.Equal(t, handler.ErrHandlerClosed, h.Handle(&slog.Record{Message: "message"}))
+}
+
+func TestEmailHandler_implicitTLS(t *testing.T) {
+	s := newFakeSMTPServer(t, true)
+	defer s.ln.Close()
+
+	h := handler.NewEmailHandler(s.Option(), []string{"a@example.com"}, func(h *handler.EmailHandler) {
+		h.HTML = false
+		h.MaxRecords = 1
+		h.Subject = "{{level}}: {{message}}"
+		h.TLSMode = handler.EmailTLSImplicit
+		h.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	})
+	defer h.Close()
+
+	// sent immediately on the MaxRecords is reached
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.ErrorLevel, Message: "héllo\nworld"}))
+	mails := s.Mails()
+	assert.Len(t, mails, 1)
+
+	msg, err := mail.ReadMessage(strings.NewReader(mails[0]))
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ERROR: héllo world", subject)
+}
+
+func TestEmailHandler_reusedRecord(t *testing.T) {
+	s := newFakeSMTPServer(t, false)
+	defer s.ln.Close()
+
+	h := handler.NewEmailHandler(s.Option(), []string{"a@example.com"}, func(h *handler.EmailHandler) {
+		h.HTML = false
+		h.Subject = "{{message}} uid={{uid}}"
+		h.Window = time.Hour
+		h.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	})
+	defer h.Close()
+
+	r := &slog.Record{Level: slog.ErrorLevel, Message: "message 1", Fields: slog.M{"uid": 1}}
+	assert.NoError(t, h.Handle(r))
+
+	// the logger reuses the record and the maps
+	r.Message = "message 2"
+	r.Fields["uid"] = 2
+	assert.NoError(t, h.Flush())
+
+	mails := s.Mails()
+	assert.Len(t, mails, 1)
+	msg, err := mail.ReadMessage(strings.NewReader(mails[0]))
+	assert.NoError(t, err)
+	assert.Equal(t, "message 1 uid=1", msg.Header.Get("Subject"))
+}
+
+func TestEmailHandler_error(t *testing.T) {
+	s := newFakeSMTPServer(t, true)
+	s.ln.Close()
+
+	h := handler.NewEmailHandler(s.Option(), []string{"a@example.com"}, func(h *handler.EmailHandler) {
+		h.TLSMode = handler.EmailTLSNone
+		h.Window = time.Hour
+	})
+	defer h.Close()
+
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.ErrorLevel, Message: "message"}))
+	assert.Error(t, h.Flush())
+	assert.Equal(t, uint64(1), h.Dropped())
+}
@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tomorrowsky/slog"
+)
+
+var (
+	// FailoverChannel the channel name of the notification record on switching handler
+	FailoverChannel = "slog.failover"
+	// DefaultCoolDown time before probe an down handler again
+	DefaultCoolDown = 30 * time.Second
+)
+
+// HealthChecker interface.
+//
+// If an handler implements it, the FailoverHandler will call HealthCheck()
+// for probe the down handler, instead of probe it by an live record.
+type HealthChecker interface {
+	HealthCheck() error
+}
+
+type failoverState struct {
+	// consecutive failures number
+	failures int
+	// the time of the handler marked as down
+	downAt time.Time
+}
+
+func (s *failoverState) isDown() bool {
+	return !s.downAt.IsZero()
+}
+
+// FailoverHandler definition
+//
+// - records will be handled by the first healthy handler in the ordered handlers,
+// the handlers that not handling the record level are skipped
+// - an handler will be marked as down after FailureThreshold consecutive failures
+// - an down handler will be probed again after CoolDown, and restored when it recovers
+// - an notification record will be emitted to the new active handler on each switch
+type FailoverHandler struct {
+	lockWrapper
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	handlers []slog.Handler
+	states   []*failoverState
+	// the index of the active handler
+	active int
+
+	// Levels for log message. if is empty, will check by the handlers
+	Levels []slog.Level
+	// FailureThreshold consecutive failures number for mark an handler as down. default is 1
+	FailureThreshold int
+	// CoolDown time before probe an down handler again. default is DefaultCoolDown
+	CoolDown time.Duration
+	// NotifyLevel the level of the notification record on switching. default is WarnLevel
+	NotifyLevel slog.Level
+	// OnSwitch callback on switching the active handler
+	OnSwitch func(from, to int, err error)
+}
+
+// NewFailover create new FailoverHandler
+func NewFailover(handlers ...slog.Handler) *FailoverHandler {
+	return NewFailoverHandler(handlers)
+}
+
+// NewFailoverHandler create new FailoverHandler
+//
+// Usage:
+// 	h := handler.NewFailoverHandler([]slog.Handler{netHandler, fileHandler, consoleHandler})
+// 	h.FailureThreshold = 3
+// 	h.CoolDown = time.Minute
+func NewFailoverHandler(handlers []slog.Handler) *FailoverHandler {
+	h := &FailoverHandler{
+		handlers: handlers,
+		states:   make([]*failoverState, len(handlers)),
+		// default options
+		FailureThreshold: 1,
+		CoolDown:         DefaultCoolDown,
+		NotifyLevel:      slog.WarnLevel,
+	}
+
+	for i := range h.states {
+		h.states[i] = &failoverState{}
+	}
+	return h
+}
+
+// Configure the handler
+func (h *FailoverHandler) Configure(fn func(h *FailoverHandler)) *FailoverHandler {
+	fn(h)
+	return h
+}
+
+// Active get the index of current active handler
+func (h *FailoverHandler) Active() int {
+	h.Lock()
+	defer h.Unlock()
+	return h.active
+}
+
+// IsHandling Check if the current level can be handling
+func (h *FailoverHandler) IsHandling(level slog.Level) bool {
+	if len(h.Levels) > 0 {
+		return slog.Levels(h.Levels).Contains(level)
+	}
+
+	for _, handler := range h.handlers {
+		if handler.IsHandling(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle log record
+func (h *FailoverHandler) Handle(r *slog.Record) error {
+	h.Lock()
+	defer h.Unlock()
+
+	var lastErr error
+	now := time.Now()
+
+	for i, handler := range h.handlers {
+		if !handler.IsHandling(r.Level) {
+			continue
+		}
+
+		st := h.states[i]
+		if st.isDown() {
+			if now.Sub(st.downAt) < h.CoolDown {
+				continue
+			}
+
+			// health probe
+			if hc, ok := handler.(HealthChecker); ok {
+				if err := hc.HealthCheck(); err != nil {
+					st.downAt = now
+					lastErr = err
+					continue
+				}
+			}
+		}
+
+		err := handler.Handle(r)
+		if err == nil {
+			st.failures = 0
+			st.downAt = time.Time{}
+
+			// restore to an higher priority handler, or the active handler is down
+			if i != h.active && (i < h.active || h.states[h.active].isDown()) {
+				h.switchTo(i, lastErr)
+			}
+			return nil
+		}
+
+		lastErr = err
+		st.failures++
+		if st.failures >= h.FailureThreshold {
+			st.downAt = now
+		}
+	}
+
+	// all handlers are failed
+	h.AddDropped(1)
+	return lastErr
+}
+
+func (h *FailoverHandler) switchTo(to int, err error) {
+	from := h.active
+	h.active = to
+
+	if h.OnSwitch != nil {
+		h.OnSwitch(from, to, err)
+	}
+
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	nr := &slog.Record{
+		Time:    time.Now(),
+		Level:   h.NotifyLevel,
+		Channel: FailoverChannel,
+		Message: fmt.Sprintf("failover: switch handler from #%d(%T) to #%d(%T)", from, h.handlers[from], to, h.handlers[to]),
+		Data: slog.M{
+			"from":  from,
+			"to":    to,
+			"error": errMsg,
+		},
+	}
+
+	// ignore error, the record is only an notification.
+	_ = h.handlers[to].Handle(nr)
+}
+
+// Flush all handlers
+func (h *FailoverHandler) Flush() error {
+	var firstErr error
+	for _, handler := range h.handlers {
+		if err := handler.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close all handlers
+func (h *FailoverHandler) Close() error {
+	var firstErr error
+	for _, handler := range h.handlers {
+		if err := handler.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
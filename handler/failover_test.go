@@ -0,0 +1,142 @@
+package handler_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+func TestFailoverHandler(t *testing.T) {
+	primary := newTestHandler()
+	secondary := newTestHandler()
+
+	var switches [][2]int
+	h := handler.NewFailover(primary, secondary).Configure(func(h *handler.FailoverHandler) {
+		h.FailureThreshold = 2
+		h.CoolDown = 50 * time.Millisecond
+		h.OnSwitch = func(from, to int, err error) {
+			switches = append(switches, [2]int{from, to})
+		}
+	})
+
+	l := slog.NewWithHandlers(h)
+	l.ReportCaller = false
+
+	l.Info("message1")
+	assert.Equal(t, []string{"message1"}, primary.messages)
+	assert.Equal(t, 0, h.Active())
+
+	// primary fails, but under the threshold
+	primary.err = errors.New("disk full")
+	l.Info("message2")
+	assert.Equal(t, []string{"message2"}, secondary.messages)
+	assert.Equal(t, 0, h.Active())
+	assert.Len(t, switches, 0)
+
+	// reach the threshold: switch to secondary
+	l.Info("message3")
+	assert.Equal(t, 1, h.Active())
+	assert.Equal(t, [][2]int{{0, 1}}, switches)
+	assert.Equal(t, "message3", secondary.messages[1])
+	// notification record
+	assert.Len(t, secondary.records, 3)
+	nr := secondary.records[2]
+	assert.Equal(t, handler.FailoverChannel, nr.Channel)
+	assert.Equal(t, slog.WarnLevel, nr.Level)
+	assert.Contains(t, nr.Message, "switch handler from #0")
+	assert.Equal(t, "disk full", nr.Data["error"])
+
+	// the primary is down, will not be called
+	primary.err = nil
+	l.Info("message4")
+	assert.Equal(t, []string{"message1"}, primary.messages)
+	assert.Equal(t, 1, h.Active())
+
+	// after cool-down: probe and restore the primary
+	time.Sleep(60 * time.Millisecond)
+	l.Info("message5")
+	assert.Equal(t, 0, h.Active())
+	assert.Equal(t, [][2]int{{0, 1}, {1, 0}}, switches)
+	assert.Len(t, primary.messages, 3)
+	assert.Equal(t, "message5", primary.messages[1])
+	assert.Equal(t, handler.FailoverChannel, primary.records[2].Channel)
+
+	// all handlers fail
+	primary.err = errors.New("primary error")
+	secondary.err = errors.New("secondary error")
+	assert.Error(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "lost"}))
+	assert.Equal(t, uint64(1), h.Dropped())
+
+	assert.NoError(t, h.Flush())
+	assert.NoError(t, h.Close())
+}
+
+func TestFailoverHandler_levels(t *testing.T) {
+	primary := newTestHandler()
+	primary.Levels = slog.DangerLevels
+	secondary := newTestHandler()
+	secondary.Levels = []slog.Level{slog.InfoLevel}
+
+	h := handler.NewFailover(primary, secondary)
+	assert.True(t, h.IsHandling(slog.InfoLevel))
+	assert.False(t, h.IsHandling(slog.DebugLevel))
+
+	// the primary is not handling the level
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "info"}))
+	assert.Len(t, primary.messages, 0)
+	assert.Equal(t, []string{"info"}, secondary.messages)
+	assert.Equal(t, 0, h.Active())
+
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.ErrorLevel, Message: "error"}))
+	assert.Equal(t, []string{"error"}, primary.messages)
+
+	// the secondary is not handling the level, the record is dropped on primary fails
+	primary.err = errors.New("disk full")
+	assert.Error(t, h.Handle(&slog.Record{Level: slog.ErrorLevel, Message: "lost"}))
+	assert.Equal(t, []string{"info"}, secondary.messages)
+	assert.Equal(t, uint64(1), h.Dropped())
+}
+
+type healthCheckHandler struct {
+	*testHandler
+	healthErr error
+	checks    int
+}
+
+func (h *healthCheckHandler) HealthCheck() error {
+	h.checks++
+	return h.healthErr
+}
+
+func TestFailoverHandler_HealthCheck(t *testing.T) {
+	primary := &healthCheckHandler{testHandler: newTestHandler()}
+	secondary := newTestHandler()
+
+	h := handler.NewFailoverHandler([]slog.Handler{primary, secondary})
+	h.CoolDown = 0
+
+	primary.err = errors.New("network down")
+	primary.healthErr = primary.err
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message1"}))
+	assert.Equal(t, 1, h.Active())
+
+	// health check failed, the primary will not handle the record
+	primary.err = nil
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message2"}))
+	assert.Equal(t, 1, primary.checks)
+	assert.Len(t, primary.messages, 0)
+
+	// recovered
+	primary.healthErr = nil
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message3"}))
+	assert.Equal(t, 0, h.Active())
+	assert.Equal(t, "message3", primary.messages[0])
+
+	assert.True(t, h.IsHandling(slog.InfoLevel))
+	h.Levels = []slog.Level{slog.ErrorLevel}
+	assert.False(t, h.IsHandling(slog.InfoLevel))
+}
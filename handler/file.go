@@ -19,6 +19,8 @@ type FileHandler struct {
 	lockWrapper
 	// LevelsWithFormatter support limit log levels and formatter
 	LevelsWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
 
 	// log file path. eg: "/var/log/my-app.log"
 	fpath string
@@ -143,9 +145,12 @@ func (h *FileHandler) Handle(r *slog.Record) (err error) {
 	// 	}
 	// }
 
+	var n int
+
 	// direct write logs
 	if h.NoBuffer {
-		_, err = h.file.Write(bts)
+		n, err = h.file.Write(bts)
+		h.AddWritten(n)
 		return
 	}
 
@@ -154,7 +159,8 @@ func (h *FileHandler) Handle(r *slog.Record) (err error) {
 		h.bufio = bufio.NewWriterSize(h.file, h.BuffSize)
 	}
 
-	_, err = h.bufio.Write(bts)
+	n, err = h.bufio.Write(bts)
+	h.AddWritten(n)
 	return
 }
 
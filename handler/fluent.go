@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/internal/msgpack"
+)
+
+// ErrFluentAck returns on the ack response is invalid
+var ErrFluentAck = errors.New("slog: invalid fluent ack response")
+
+// fluentEntry an encoded Forward mode entry: [time, record]
+type fluentEntry struct {
+	tag  string
+	data []byte
+}
+
+// FluentForwardHandler definition. send records to Fluentd/Fluent Bit by the Forward protocol.
+//
+// - the records are encoded as the msgpack Forward mode entries: [tag, [[time, record], ...], option]
+// - the tag is the Record.Channel, with the TagPrefix
+// - the time is encoded as the EventTime extension type
+// - records are batched, and sent on the batch is full, or every FlushInterval
+// - with RequireAck, it will wait for the ack of each chunk, and resend the chunk on failed(at-least-once)
+type FluentForwardHandler struct {
+	lockWrapper
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	network string
+	addr    string
+	conn    net.Conn
+	reader  *bufio.Reader
+
+	pending []fluentEntry
+
+	flusher *intervalFlusher
+	closed  bool
+
+	// Levels for log message
+	Levels []slog.Level
+	// TagPrefix for the tag. eg: "app" will make the tag "app.{channel}"
+	TagPrefix string
+	// BatchSize max records of an batch. default is 100
+	BatchSize int
+	// FlushInterval for send the batched records, 0 to disable the timed flush. default is 1s
+	FlushInterval time.Duration
+	// RequireAck use the "chunk" option for wait the ack from the server
+	RequireAck bool
+	// AckTimeout for wait the ack. default is 5s
+	AckTimeout time.Duration
+	// MaxRetries for resend an chunk on failed. default is 3
+	MaxRetries int
+	// Backoff settings for wait before resend
+	Backoff Backoff
+	// DialTimeout for connect the server. default is 5s
+	DialTimeout time.Duration
+	// WriteTimeout for send an chunk. default is 5s
+	WriteTimeout time.Duration
+}
+
+// NewFluentForward create new FluentForwardHandler. see NewFluentForwardHandler()
+func NewFluentForward(addr string, levels []slog.Level, fns ...func(h *FluentForwardHandler)) (*FluentForwardHandler, error) {
+	return NewFluentForwardHandler(addr, levels, fns...)
+}
+
+// NewFluentForwardHandler create new FluentForwardHandler, and start the flush daemon.
+// addr is the TCP address, or an unix socket path with prefix "unix://"
+//
+// Usage:
+// 	h, err := handler.NewFluentForwardHandler("127.0.0.1:24224", slog.AllLevels, func(h *handler.FluentForwardHandler) {
+// 		h.TagPrefix = "myapp"
+// 		h.RequireAck = true
+// 	})
+// 	defer h.Close()
+func NewFluentForwardHandler(addr string, levels []slog.Level, fns ...func(h *FluentForwardHandler)) (*FluentForwardHandler, error) {
+	h := &FluentForwardHandler{
+		network: "tcp",
+		addr:    addr,
+		Levels:  levels,
+		// default options
+		BatchSize:     100,
+		FlushInterval: time.Second,
+		AckTimeout:    5 * time.Second,
+		MaxRetries:    3,
+		Backoff:       DefaultBackoff,
+		DialTimeout:   5 * time.Second,
+		WriteTimeout:  5 * time.Second,
+	}
+
+	if len(addr) > 7 && addr[:7] == "unix://" {
+		h.network, h.addr = "unix", addr[7:]
+	}
+
+	for _, fn := range fns {
+		fn(h)
+	}
+
+	if err := h.connect(); err != nil {
+		return nil, err
+	}
+
+	h.flusher = startIntervalFlusher(h.FlushInterval, func() {
+		_ = h.Flush()
+	})
+	return h, nil
+}
+
+func (h *FluentForwardHandler) connect() error {
+	conn, err := net.DialTimeout(h.network, h.addr, h.DialTimeout)
+	if err != nil {
+		return err
+	}
+
+	h.conn = conn
+	h.reader = bufio.NewReader(conn)
+	return nil
+}
+
+func (h *FluentForwardHandler) disconnect() {
+	if h.conn != nil {
+		_ = h.conn.Close()
+		h.conn, h.reader = nil, nil
+	}
+}
+
+// IsHandling Check if the current level can be handling
+func (h *FluentForwardHandler) IsHandling(level slog.Level) bool {
+	return slog.Levels(h.Levels).Contains(level)
+}
+
+// Tag get the tag for the record
+func (h *FluentForwardHandler) Tag(r *slog.Record) string {
+	tag := r.Channel
+	if tag == "" {
+		tag = slog.DefaultChannelName
+	}
+
+	if h.TagPrefix != "" {
+		return h.TagPrefix + "." + tag
+	}
+	return tag
+}
+
+// Handle log record
+func (h *FluentForwardHandler) Handle(r *slog.Record) error {
+	entry := fluentEntry{tag: h.Tag(r), data: encodeFluentEntry(r)}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.closed {
+		return ErrHandlerClosed
+	}
+
+	h.pending = append(h.pending, entry)
+	if len(h.pending) >= h.BatchSize {
+		return h.flush()
+	}
+	return nil
+}
+
+// encode the record to entry: [time, record]
+func encodeFluentEntry(r *slog.Record) []byte {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+
+	size := 3 + len(r.Fields)
+	if r.Caller != nil {
+		size++
+	}
+	if len(r.Data) > 0 {
+		size++
+	}
+	if len(r.Extra) > 0 {
+		size++
+	}
+
+	b := msgpack.AppendArrayHeader(make([]byte, 0, 256), 2)
+	b = msgpack.AppendEventTime(b, r.Time)
+	b = msgpack.AppendMapHeader(b, size)
+
+	b = msgpack.AppendString(b, slog.FieldKeyLevel)
+	b = msgpack.AppendString(b, r.LevelName())
+	b = msgpack.AppendString(b, slog.FieldKeyChannel)
+	b = msgpack.AppendString(b, r.Channel)
+	b = msgpack.AppendString(b, slog.FieldKeyMessage)
+	b = msgpack.AppendString(b, r.Message)
+
+	if r.Caller != nil {
+		b = msgpack.AppendString(b, slog.FieldKeyCaller)
+		b = msgpack.AppendString(b, r.Caller.File+":"+strconv.Itoa(r.Caller.Line))
+	}
+	if len(r.Data) > 0 {
+		b = msgpack.AppendString(b, slog.FieldKeyData)
+		b = msgpack.AppendValue(b, map[string]interface{}(r.Data))
+	}
+	if len(r.Extra) > 0 {
+		b = msgpack.AppendString(b, slog.FieldKeyExtra)
+		b = msgpack.AppendValue(b, map[string]interface{}(r.Extra))
+	}
+
+	for k, v := range r.Fields {
+		switch k {
+		case slog.FieldKeyLevel, slog.FieldKeyChannel, slog.FieldKeyMessage, slog.FieldKeyCaller, slog.FieldKeyData, slog.FieldKeyExtra:
+			k = "fields." + k
+		}
+
+		b = msgpack.AppendString(b, k)
+		b = msgpack.AppendValue(b, v)
+	}
+	return b
+}
+
+// flush the pending entries. the consecutive entries with same tag will be sent in an chunk.
+func (h *FluentForwardHandler) flush() error {
+	var lastErr error
+	for len(h.pending) > 0 {
+		n := 1
+		for n < len(h.pending) && h.pending[n].tag == h.pending[0].tag {
+			n++
+		}
+
+		if err := h.send(h.pending[0].tag, h.pending[:n]); err != nil {
+			h.AddDropped(n)
+			lastErr = err
+		}
+
+		h.pending = h.pending[n:]
+	}
+
+	h.pending = nil
+	return lastErr
+}
+
+// send an chunk: [tag, [entry, ...], {"chunk": id}]
+func (h *FluentForwardHandler) send(tag string, entries []fluentEntry) (err error) {
+	var chunkID string
+	if h.RequireAck {
+		if chunkID, err = newChunkID(); err != nil {
+			return err
+		}
+	}
+
+	b := msgpack.AppendArrayHeader(nil, 3)
+	b = msgpack.AppendString(b, tag)
+	b = msgpack.AppendArrayHeader(b, len(entries))
+	for _, entry := range entries {
+		b = append(b, entry.data...)
+	}
+
+	b = msgpack.AppendMapHeader(b, 1)
+	if chunkID != "" {
+		b = msgpack.AppendString(b, "chunk")
+		b = msgpack.AppendString(b, chunkID)
+	} else {
+		b = msgpack.AppendString(b, "size")
+		b = msgpack.AppendInt(b, int64(len(entries)))
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err = h.sendChunk(b, chunkID); err == nil {
+			h.AddWritten(len(b))
+			return nil
+		}
+
+		// reconnect on next send
+		h.disconnect()
+		if attempt >= h.MaxRetries {
+			return err
+		}
+		time.Sleep(h.Backoff.Duration(attempt))
+	}
+}
+
+func (h *FluentForwardHandler) sendChunk(b []byte, chunkID string) error {
+	if h.conn == nil {
+		if err := h.connect(); err != nil {
+			return err
+		}
+	}
+
+	if h.WriteTimeout > 0 {
+		_ = h.conn.SetWriteDeadline(time.Now().Add(h.WriteTimeout))
+	}
+	if _, err := h.conn.Write(b); err != nil {
+		return err
+	}
+
+	if chunkID == "" {
+		return nil
+	}
+
+	// wait the ack: {"ack": id}
+	_ = h.conn.SetReadDeadline(time.Now().Add(h.AckTimeout))
+	resp, err := msgpack.NewDecoder(h.reader).Decode()
+	if err != nil {
+		return err
+	}
+
+	if mp, ok := resp.(map[string]interface{}); !ok || mp["ack"] != chunkID {
+		return ErrFluentAck
+	}
+	return nil
+}
+
+func newChunkID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(id), nil
+}
+
+// Flush send the pending records
+func (h *FluentForwardHandler) Flush() error {
+	h.Lock()
+	defer h.Unlock()
+	return h.flush()
+}
+
+// Close stop the flush daemon, send the pending records and close the connection
+func (h *FluentForwardHandler) Close() error {
+	h.Lock()
+	if h.closed {
+		h.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.Unlock()
+
+	h.flusher.Stop()
+
+	h.Lock()
+	defer h.Unlock()
+
+	err := h.flush()
+	h.disconnect()
+	return err
+}
@@ -0,0 +1,196 @@
+package handler_test
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+	"github.com/tomorrowsky/slog/internal/msgpack"
+)
+
+type fluentEvent struct {
+	tag    string
+	time   time.Time
+	record map[string]interface{}
+}
+
+// fakeFluentServer an local Forward protocol server for testing
+type fakeFluentServer struct {
+	ln net.Listener
+	mu sync.Mutex
+	// chunks number that will not be acked, the connection will be closed.
+	dropAcks int
+	events   []fluentEvent
+	chunks   int
+}
+
+func newFakeFluentServer(t *testing.T) *fakeFluentServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	s := &fakeFluentServer{ln: ln}
+	go s.serve()
+	return s
+}
+
+func (s *fakeFluentServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *fakeFluentServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	dec := msgpack.NewDecoder(conn)
+	for {
+		v, err := dec.Decode()
+		if err != nil {
+			return
+		}
+
+		// [tag, [[time, record], ...], option]
+		msg := v.([]interface{})
+		tag := msg[0].(string)
+
+		s.mu.Lock()
+		s.chunks++
+		for _, item := range msg[1].([]interface{}) {
+			entry := item.([]interface{})
+			tm, _ := entry[0].(msgpack.Ext).EventTime()
+			s.events = append(s.events, fluentEvent{tag: tag, time: tm, record: entry[1].(map[string]interface{})})
+		}
+
+		chunk, hasChunk := msg[2].(map[string]interface{})["chunk"]
+		drop := hasChunk && s.dropAcks > 0
+		if drop {
+			s.dropAcks--
+		}
+		s.mu.Unlock()
+
+		if drop {
+			return
+		}
+
+		if hasChunk {
+			if _, err = conn.Write(msgpack.AppendValue(nil, map[string]interface{}{"ack": chunk})); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *fakeFluentServer) Events() []fluentEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fluentEvent(nil), s.events...)
+}
+
+func (s *fakeFluentServer) waitEvents(n int) []fluentEvent {
+	for i := 0; i < 300; i++ {
+		if evs := s.Events(); len(evs) >= n {
+			return evs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return s.Events()
+}
+
+func TestFluentForwardHandler(t *testing.T) {
+	srv := newFakeFluentServer(t)
+	defer srv.ln.Close()
+
+	h, err := handler.NewFluentForwardHandler(srv.ln.Addr().String(), slog.AllLevels, func(h *handler.FluentForwardHandler) {
+		h.TagPrefix = "myapp"
+		h.BatchSize = 3
+		h.FlushInterval = time.Hour
+	})
+	assert.NoError(t, err)
+
+	tm := time.Unix(1614600000, 123456789)
+	assert.NoError(t, h.Handle(&slog.Record{Time: tm, Level: slog.InfoLevel, Channel: "web", Message: "message 1",
+		Data: slog.M{"uid": 23, "user": slog.M{"name": "inhere"}}, Fields: slog.M{"ip": "127.0.0.1"}}))
+	assert.NoError(t, h.Handle(&slog.Record{Time: tm, Level: slog.WarnLevel, Channel: "web", Message: "message 2"}))
+	assert.Len(t, srv.Events(), 0)
+
+	// batch is full
+	assert.NoError(t, h.Handle(&slog.Record{Time: tm, Level: slog.ErrorLevel, Channel: "db", Message: "message 3"}))
+
+	evs := srv.waitEvents(3)
+	assert.Len(t, evs, 3)
+	assert.Equal(t, "myapp.web", evs[0].tag)
+	assert.True(t, tm.Equal(evs[0].time))
+	assert.Equal(t, "message 1", evs[0].record["message"])
+	assert.Equal(t, "INFO", evs[0].record["level"])
+	assert.Equal(t, "127.0.0.1", evs[0].record["ip"])
+	// the nested slog.M is encoded as map
+	assert.Equal(t, map[string]interface{}{
+		"uid":  int64(23),
+		"user": map[string]interface{}{"name": "inhere"},
+	}, evs[0].record["data"])
+	assert.Equal(t, "myapp.db", evs[2].tag)
+
+	srv.mu.Lock()
+	assert.Equal(t, 2, srv.chunks)
+	srv.mu.Unlock()
+
+	// pending records will be sent on close
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message 4"}))
+	assert.NoError(t, h.Close())
+	evs = srv.waitEvents(4)
+	assert.Equal(t, "myapp.application", evs[3].tag)
+	assert.Equal(t, handler.ErrHandlerClosed, h.Handle(&slog.Record{}))
+}
+
+func TestFluentForwardHandler_ack(t *testing.T) {
+	srv := newFakeFluentServer(t)
+	defer srv.ln.Close()
+	srv.dropAcks = 1
+
+	h, err := handler.NewFluentForwardHandler(srv.ln.Addr().String(), slog.AllLevels, func(h *handler.FluentForwardHandler) {
+		h.RequireAck = true
+		h.AckTimeout = time.Second
+		h.Backoff = fastBackoff
+		h.FlushInterval = 20 * time.Millisecond
+	})
+	assert.NoError(t, err)
+	defer h.Close()
+
+	// will be sent by the flush daemon
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message"}))
+
+	// the first chunk is not acked, so it will be resent.
+	evs := srv.waitEvents(2)
+	assert.Len(t, evs, 2)
+	assert.Equal(t, "message", evs[1].record["message"])
+
+	assert.NoError(t, h.Flush())
+	assert.Equal(t, uint64(0), h.Dropped())
+	assert.True(t, h.Written() > 0)
+}
+
+func TestFluentForwardHandler_zeroInterval(t *testing.T) {
+	srv := newFakeFluentServer(t)
+	defer srv.ln.Close()
+
+	h, err := handler.NewFluentForwardHandler(srv.ln.Addr().String(), slog.AllLevels, func(h *handler.FluentForwardHandler) {
+		h.FlushInterval = 0
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message"}))
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, srv.Events(), 0)
+
+	// pending records will be sent on close
+	assert.NoError(t, h.Close())
+	assert.Len(t, srv.waitEvents(1), 1)
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/tomorrowsky/slog"
+)
+
+// GELFCompression the compression type for the GELF UDP messages
+type GELFCompression uint8
+
+// GELF compression types
+const (
+	GELFCompressNone GELFCompression = iota
+	GELFCompressGzip
+	GELFCompressZlib
+)
+
+const (
+	// gelfChunkHeaderLen magic(2) + message id(8) + sequence number(1) + sequence count(1)
+	gelfChunkHeaderLen = 12
+	gelfMaxChunks      = 128
+)
+
+var (
+	// DefaultGELFChunkSize max size of an UDP packet.
+	DefaultGELFChunkSize = 1420
+	// ErrGELFTooLarge returns on the message need more than 128 chunks
+	ErrGELFTooLarge = errors.New("slog: the GELF message is too large")
+
+	gelfChunkMagic = []byte{0x1e, 0x0f}
+)
+
+// GELFHandler definition. send GELF messages to Graylog.
+//
+// - "udp": the message is compressed by Compress, and split to chunks if larger than ChunkSize
+// - "tcp": the message is not compressed, and framed by an null byte
+type GELFHandler struct {
+	lockWrapper
+	LevelsWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	network string
+	addr    string
+	conn    net.Conn
+
+	// ChunkSize max size of an UDP packet. default is DefaultGELFChunkSize.
+	// the DefaultGELFChunkSize is used on it is not larger than the chunk header(12 bytes)
+	ChunkSize int
+	// Compress type for UDP messages. default is GELFCompressGzip
+	Compress GELFCompression
+	// DialTimeout for connect the server. default is 5s
+	DialTimeout time.Duration
+	// WriteTimeout for send an message. default is 5s
+	WriteTimeout time.Duration
+}
+
+// NewGELF create new GELFHandler. see NewGELFHandler()
+func NewGELF(network, addr string, levels []slog.Level) (*GELFHandler, error) {
+	return NewGELFHandler(network, addr, levels)
+}
+
+// NewGELFHandler create new GELFHandler. network allow: "udp", "tcp"
+//
+// Usage:
+// 	h, err := handler.NewGELFHandler("udp", "graylog:12201", slog.AllLevels)
+func NewGELFHandler(network, addr string, levels []slog.Level) (*GELFHandler, error) {
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	default:
+		return nil, errors.New("slog: unsupported GELF network " + network)
+	}
+
+	h := &GELFHandler{
+		network: network,
+		addr:    addr,
+		// init levels
+		LevelsWithFormatter: newLvsFormatter(levels),
+		// default options
+		ChunkSize:    DefaultGELFChunkSize,
+		Compress:     GELFCompressGzip,
+		DialTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+
+	h.SetFormatter(slog.NewGELFFormatter())
+
+	if err := h.connect(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Configure the handler
+func (h *GELFHandler) Configure(fn func(h *GELFHandler)) *GELFHandler {
+	fn(h)
+	return h
+}
+
+func (h *GELFHandler) isUDP() bool {
+	return h.network[:3] == "udp"
+}
+
+func (h *GELFHandler) connect() (err error) {
+	h.conn, err = net.DialTimeout(h.network, h.addr, h.DialTimeout)
+	return
+}
+
+// Handle log record
+func (h *GELFHandler) Handle(r *slog.Record) error {
+	bts, err := h.Formatter().Format(r)
+	if err != nil {
+		return err
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.conn == nil {
+		if err = h.connect(); err != nil {
+			return err
+		}
+	}
+
+	if h.isUDP() {
+		err = h.writeUDP(bts)
+	} else {
+		err = h.writeTCP(append(bts, 0))
+	}
+
+	if err == nil {
+		h.AddWritten(len(bts))
+	}
+	return err
+}
+
+func (h *GELFHandler) writeUDP(bts []byte) (err error) {
+	if bts, err = h.compress(bts); err != nil {
+		return err
+	}
+
+	chunkSize := h.ChunkSize
+	if chunkSize <= gelfChunkHeaderLen {
+		chunkSize = DefaultGELFChunkSize
+	}
+
+	if len(bts) <= chunkSize {
+		return h.write(bts)
+	}
+
+	size := chunkSize - gelfChunkHeaderLen
+	count := (len(bts) + size - 1) / size
+	if count > gelfMaxChunks {
+		h.AddDropped(1)
+		return ErrGELFTooLarge
+	}
+
+	id := make([]byte, 8)
+	if _, err = rand.Read(id); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 0, chunkSize)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(bts) {
+			end = len(bts)
+		}
+
+		chunk = append(chunk[:0], gelfChunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, bts[i*size:end]...)
+
+		if err = h.write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write to TCP connection, will reconnect once on failed.
+func (h *GELFHandler) writeTCP(bts []byte) error {
+	if err := h.write(bts); err == nil {
+		return nil
+	}
+
+	_ = h.conn.Close()
+	if err := h.connect(); err != nil {
+		h.conn = nil
+		return err
+	}
+	return h.write(bts)
+}
+
+func (h *GELFHandler) write(bts []byte) error {
+	if h.WriteTimeout > 0 {
+		_ = h.conn.SetWriteDeadline(time.Now().Add(h.WriteTimeout))
+	}
+
+	_, err := h.conn.Write(bts)
+	return err
+}
+
+func (h *GELFHandler) compress(bts []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	switch h.Compress {
+	case GELFCompressGzip:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(bts); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case GELFCompressZlib:
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(bts); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return bts, nil
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Flush no operation
+func (h *GELFHandler) Flush() error {
+	return nil
+}
+
+// Close the connection
+func (h *GELFHandler) Close() error {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.conn == nil {
+		return nil
+	}
+
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
@@ -0,0 +1,151 @@
+package handler_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+// read an GELF message from UDP listener, will join the chunks.
+func readGELFPacket(t *testing.T, conn net.PacketConn) []byte {
+	buf := make([]byte, 65536)
+	var chunks [][]byte
+
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+
+		pkt := append([]byte(nil), buf[:n]...)
+		if !bytes.HasPrefix(pkt, []byte{0x1e, 0x0f}) {
+			return pkt
+		}
+
+		seq, count := int(pkt[10]), int(pkt[11])
+		if chunks == nil {
+			chunks = make([][]byte, count)
+		}
+
+		chunks[seq] = pkt[12:]
+		if seq == count-1 {
+			return bytes.Join(chunks, nil)
+		}
+	}
+}
+
+func TestGELFHandler_udp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	h, err := handler.NewGELFHandler("udp", conn.LocalAddr().String(), slog.AllLevels)
+	assert.NoError(t, err)
+	defer h.Close()
+
+	l := slog.NewWithHandlers(h)
+	l.ReportCaller = false
+	l.WithData(slog.M{"uid": 23, "user": slog.M{"name": "tom"}}).Error("error message\nstack trace")
+
+	zr, err := gzip.NewReader(bytes.NewReader(readGELFPacket(t, conn)))
+	assert.NoError(t, err)
+	bts, err := ioutil.ReadAll(zr)
+	assert.NoError(t, err)
+
+	msg := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(bts, &msg))
+	assert.Equal(t, "1.1", msg["version"])
+	assert.Equal(t, "error message", msg["short_message"])
+	assert.Equal(t, "error message\nstack trace", msg["full_message"])
+	assert.Equal(t, float64(3), msg["level"])
+	assert.Equal(t, float64(23), msg["_uid"])
+	assert.Equal(t, "tom", msg["_user.name"])
+	assert.Equal(t, "application", msg["_channel"])
+
+	// chunking and zlib
+	h.Configure(func(h *handler.GELFHandler) {
+		h.ChunkSize = 64
+		h.Compress = handler.GELFCompressZlib
+	})
+	l.Info(strings.Repeat("long message ", 100))
+
+	zr2, err := zlib.NewReader(bytes.NewReader(readGELFPacket(t, conn)))
+	assert.NoError(t, err)
+	bts, err = ioutil.ReadAll(zr2)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(bts, &msg))
+	assert.Equal(t, float64(6), msg["level"])
+	assert.Equal(t, strings.TrimSpace(strings.Repeat("long message ", 100)), msg["short_message"])
+
+	// too large
+	h.Configure(func(h *handler.GELFHandler) {
+		h.ChunkSize = 13
+		h.Compress = handler.GELFCompressNone
+	})
+	assert.Equal(t, handler.ErrGELFTooLarge, h.Handle(&slog.Record{Message: strings.Repeat("a", 200)}))
+	assert.Equal(t, uint64(1), h.Dropped())
+	// the invalid chunk size use the default
+	for _, size := range []int{0, 12} {
+		h.ChunkSize = size
+		assert.NoError(t, h.Handle(&slog.Record{Message: strings.Repeat("a", 200)}))
+		assert.Contains(t, string(readGELFPacket(t, conn)), strings.Repeat("a", 200))
+	}
+}
+
+func TestGELFHandler_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	msgs := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			msgs <- strings.TrimSuffix(line, "\x00")
+		}
+	}()
+
+	h, err := handler.NewGELFHandler("tcp", ln.Addr().String(), slog.AllLevels)
+	assert.NoError(t, err)
+	defer h.Close()
+
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.WarnLevel, Message: "message 1", Fields: slog.M{"id": 1}}))
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.DebugLevel, Message: "message 2"}))
+
+	for i, want := range []string{"message 1", "message 2"} {
+		select {
+		case str := <-msgs:
+			msg := map[string]interface{}{}
+			assert.NoError(t, json.Unmarshal([]byte(str), &msg))
+			assert.Equal(t, want, msg["short_message"])
+			if i == 0 {
+				assert.Equal(t, float64(4), msg["level"])
+				assert.Equal(t, float64(1), msg["_id_"])
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout for receive the message")
+		}
+	}
+}
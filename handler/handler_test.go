@@ -101,3 +101,28 @@ func TestBufferWrapper(t *testing.T) {
 
 	l.FlushAll()
 }
+
+// testHandler for testing, it will save the messages of handled records.
+type testHandler struct {
+	handler.LevelsWithFormatter
+	handler.NopFlushClose
+	err      error
+	messages []string
+	records  []*slog.Record
+}
+
+func newTestHandler() *testHandler {
+	return &testHandler{
+		LevelsWithFormatter: handler.LevelsWithFormatter{Levels: slog.AllLevels},
+	}
+}
+
+func (h *testHandler) Handle(r *slog.Record) error {
+	if h.err != nil {
+		return h.err
+	}
+
+	h.messages = append(h.messages, r.Message)
+	h.records = append(h.records, r)
+	return nil
+}
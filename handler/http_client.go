@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HTTPError returns on the response status code is not 2xx
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error string
+func (e *HTTPError) Error() string {
+	return "slog: http response status " + strconv.Itoa(e.StatusCode) + ": " + e.Body
+}
+
+// Retryable check the request can be retried. status is 429 or 5xx
+func (e *HTTPError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// send the request with retry, return the response body.
+// newReq will be called for create new request on each attempt.
+//
+// retry on the network error, or the response status is 429, 5xx.
+// will use the Retry-After header as the wait time if exists.
+func doHTTPWithRetry(client *http.Client, newReq func() (*http.Request, error), maxRetries int, backoff Backoff) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		body, wait, err := doHTTP(client, newReq)
+		if err == nil {
+			return body, nil
+		}
+
+		if he, ok := err.(*HTTPError); ok && !he.Retryable() {
+			return body, err
+		}
+
+		if attempt >= maxRetries {
+			return body, err
+		}
+
+		if wait <= 0 {
+			wait = backoff.Duration(attempt)
+		}
+		time.Sleep(wait)
+	}
+}
+
+func doHTTP(client *http.Client, newReq func() (*http.Request, error)) (body []byte, wait time.Duration, err error) {
+	req, err := newReq()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err = ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(sec) * time.Second
+		}
+		return body, wait, &HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return body, 0, nil
+}
+
+// intervalFlusher call the flush func on every interval, until stopped.
+type intervalFlusher struct {
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// start the flusher. the interval <= 0 will disable the timed flush, but the Stop() still can be called.
+func startIntervalFlusher(interval time.Duration, flush func()) *intervalFlusher {
+	f := &intervalFlusher{stop: make(chan struct{})}
+	if interval <= 0 {
+		return f
+	}
+
+	f.wg.Add(1)
+
+	go func() {
+		defer f.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+	return f
+}
+
+// Stop the flusher, and wait for the running flush is finished.
+func (f *intervalFlusher) Stop() {
+	close(f.stop)
+	f.wg.Wait()
+}
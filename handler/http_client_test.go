@@ -0,0 +1,95 @@
+package handler_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+// the 0 interval will disable the timed flush, the records are sent on close.
+func TestBatchHandlers_zeroInterval(t *testing.T) {
+	tests := []struct {
+		name  string
+		newFn func(url string) slog.Handler
+	}{
+		{"otlp", func(url string) slog.Handler {
+			return handler.NewOTLPHTTPHandler(url, slog.AllLevels, func(h *handler.OTLPHTTPHandler) {
+				h.FlushInterval = 0
+			})
+		}},
+		{"loki", func(url string) slog.Handler {
+			return handler.NewLokiHandler(url, slog.AllLevels, func(h *handler.LokiHandler) {
+				h.BatchWait = 0
+			})
+		}},
+	}
+
+	for _, tt := range tests {
+		srv := newRecordServer()
+		h := tt.newFn(srv.URL)
+
+		assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message"}), tt.name)
+		time.Sleep(10 * time.Millisecond)
+		assert.Len(t, srv.Bodies(), 0, tt.name)
+
+		assert.NoError(t, h.Close(), tt.name)
+		assert.Len(t, srv.Bodies(), 1, tt.name)
+		srv.Close()
+	}
+
+	var bulks int
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bulks++
+		_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer es.Close()
+
+	eh := handler.NewElasticsearchHandler(es.URL, slog.AllLevels, func(h *handler.ElasticsearchHandler) {
+		h.FlushInterval = 0
+	})
+	assert.NoError(t, eh.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message"}))
+	assert.Equal(t, 0, bulks)
+	assert.NoError(t, eh.Close())
+	assert.Equal(t, 1, bulks)
+
+	hec := newFakeHECServer(0)
+	defer hec.Close()
+
+	sh := handler.NewSplunkHECHandler(hec.URL, "token", slog.AllLevels, func(h *handler.SplunkHECHandler) {
+		h.FlushInterval = 0
+	})
+	assert.NoError(t, sh.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message"}))
+	assert.Len(t, hec.Events(), 0)
+	assert.NoError(t, sh.Close())
+	assert.Len(t, hec.Events(), 1)
+}
+
+// the 0 window will send each record immediately
+func TestAlertHandlers_zeroWindow(t *testing.T) {
+	srv := newRecordServer()
+	defer srv.Close()
+
+	wh := handler.NewWebhookHandler(srv.URL, slog.AllLevels, func(h *handler.WebhookHandler) {
+		h.Window = 0
+	})
+	assert.NoError(t, wh.Handle(&slog.Record{Level: slog.ErrorLevel, Message: "message"}))
+	assert.Len(t, srv.Bodies(), 1)
+	assert.NoError(t, wh.Close())
+
+	s := newFakeSMTPServer(t, false)
+	defer s.ln.Close()
+
+	eh := handler.NewEmailHandler(s.Option(), []string{"a@example.com"}, func(h *handler.EmailHandler) {
+		h.Window = 0
+		h.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	})
+	assert.NoError(t, eh.Handle(&slog.Record{Level: slog.ErrorLevel, Message: "message"}))
+	assert.Len(t, s.Mails(), 1)
+	assert.NoError(t, eh.Close())
+}
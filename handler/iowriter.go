@@ -10,6 +10,7 @@ import (
 type IOWriterHandler struct {
 	lockWrapper
 	LevelsWithFormatter
+	slog.HandlerMetrics
 
 	// Output io.WriteCloser
 	Output io.Writer
@@ -55,6 +56,7 @@ func (h *IOWriterHandler) Handle(record *slog.Record) error {
 	h.Lock()
 	defer h.Unlock()
 
-	_, err = h.Output.Write(bts)
+	n, err := h.Output.Write(bts)
+	h.AddWritten(n)
 	return err
 }
@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomorrowsky/slog"
+)
+
+// label names for the record attributes
+const (
+	LokiLabelChannel  = "channel"
+	LokiLabelLevel    = "level"
+	LokiLabelHostname = "hostname"
+)
+
+// lokiStream an stream of the push request
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	// item: [unix nano timestamp, line]
+	Values [][2]string `json:"values"`
+}
+
+// LokiHandler definition. push records to Grafana Loki by the push API.
+//
+// - the stream labels are from the Labels of the record attributes, and the StaticLabels
+// - the log line is formatted by the formatter. default is slog.LogfmtFormatter
+// - records are batched, the entries are kept in order within each stream
+// - the request will be retried on the network error, 429 and 5xx
+//
+// NOTICE: only support the JSON push request, the snappy-protobuf is not supported.
+type LokiHandler struct {
+	lockWrapper
+	LevelsWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	// streams by the labels key, and the order of streams
+	streams map[string]*lokiStream
+	order   []string
+	pending int
+
+	flusher  *intervalFlusher
+	closed   bool
+	hostname string
+
+	// Endpoint the push API url. eg: "http://localhost:3100/loki/api/v1/push"
+	Endpoint string
+	// Labels the record attributes as the stream labels. default is channel, level
+	//
+	// allow: "channel", "level", "hostname", and the key of Record.Fields
+	Labels []string
+	// StaticLabels add to all streams. eg: {"job": "myapp"}
+	StaticLabels map[string]string
+	// TenantID for the multi-tenant Loki, will set as the X-Scope-OrgID header
+	TenantID string
+	// Headers for the request. eg: auth header
+	Headers map[string]string
+	// Client for send request. default timeout is 10s
+	Client *http.Client
+	// BatchSize max records of an request. default is 100
+	BatchSize int
+	// BatchWait max wait time before send the batched records, 0 to disable the timed flush. default is 1s
+	BatchWait time.Duration
+	// MaxRetries for resend an request on failed. default is 3
+	MaxRetries int
+	// Backoff settings for wait before resend
+	Backoff Backoff
+}
+
+// NewLoki create new LokiHandler. see NewLokiHandler()
+func NewLoki(endpoint string, levels []slog.Level, fns ...func(h *LokiHandler)) *LokiHandler {
+	return NewLokiHandler(endpoint, levels, fns...)
+}
+
+// NewLokiHandler create new LokiHandler, and start the flush daemon.
+//
+// Usage:
+// 	h := handler.NewLokiHandler("http://localhost:3100/loki/api/v1/push", slog.AllLevels, func(h *handler.LokiHandler) {
+// 		h.StaticLabels = map[string]string{"job": "myapp"}
+// 		h.TenantID = "team-a"
+// 	})
+// 	defer h.Close()
+func NewLokiHandler(endpoint string, levels []slog.Level, fns ...func(h *LokiHandler)) *LokiHandler {
+	h := &LokiHandler{
+		streams:  make(map[string]*lokiStream),
+		Endpoint: endpoint,
+		// init levels
+		LevelsWithFormatter: newLvsFormatter(levels),
+		// default options
+		Labels:     []string{LokiLabelChannel, LokiLabelLevel},
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		BatchSize:  100,
+		BatchWait:  time.Second,
+		MaxRetries: 3,
+		Backoff:    DefaultBackoff,
+	}
+
+	h.hostname, _ = os.Hostname()
+	h.SetFormatter(slog.NewLogfmtFormatter(func(f *slog.LogfmtFormatter) {
+		f.Fields = []string{slog.FieldKeyLevel, slog.FieldKeyCaller, slog.FieldKeyMessage, slog.FieldKeyData, slog.FieldKeyExtra}
+	}))
+
+	for _, fn := range fns {
+		fn(h)
+	}
+
+	h.flusher = startIntervalFlusher(h.BatchWait, func() {
+		_ = h.Flush()
+	})
+	return h
+}
+
+// StreamLabels get the stream labels for the record
+func (h *LokiHandler) StreamLabels(r *slog.Record) map[string]string {
+	labels := make(map[string]string, len(h.Labels)+len(h.StaticLabels))
+	for k, v := range h.StaticLabels {
+		labels[lokiLabelName(k)] = v
+	}
+
+	for _, name := range h.Labels {
+		var val string
+		switch name {
+		case LokiLabelChannel:
+			val = r.Channel
+		case LokiLabelLevel:
+			val = r.Level.LowerName()
+		case LokiLabelHostname:
+			val = h.hostname
+		default:
+			if v, ok := r.Fields[name]; ok {
+				val = slog.EncodeToString(v)
+			}
+		}
+
+		// the empty label will be ignored by Loki
+		if val != "" {
+			labels[lokiLabelName(name)] = val
+		}
+	}
+	return labels
+}
+
+// Handle log record
+func (h *LokiHandler) Handle(r *slog.Record) error {
+	line, err := h.Formatter().Format(r)
+	if err != nil {
+		return err
+	}
+
+	labels := h.StreamLabels(r)
+	key := lokiLabelsKey(labels)
+
+	ts := r.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.closed {
+		return ErrHandlerClosed
+	}
+
+	st, ok := h.streams[key]
+	if !ok {
+		st = &lokiStream{Stream: labels}
+		h.streams[key] = st
+		h.order = append(h.order, key)
+	}
+
+	st.Values = append(st.Values, [2]string{
+		strconv.FormatInt(ts.UnixNano(), 10),
+		strings.TrimRight(string(line), "\n"),
+	})
+
+	if h.pending++; h.pending >= h.BatchSize {
+		return h.flush()
+	}
+	return nil
+}
+
+func (h *LokiHandler) flush() error {
+	if h.pending == 0 {
+		return nil
+	}
+
+	req := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{Streams: make([]*lokiStream, 0, len(h.order))}
+
+	for _, key := range h.order {
+		req.Streams = append(req.Streams, h.streams[key])
+	}
+
+	n := h.pending
+	h.streams = make(map[string]*lokiStream)
+	h.order = nil
+	h.pending = 0
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		h.AddDropped(n)
+		return err
+	}
+
+	_, err = doHTTPWithRetry(h.Client, func() (*http.Request, error) {
+		hr, err := http.NewRequest(http.MethodPost, h.Endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		hr.Header.Set("Content-Type", "application/json")
+		if h.TenantID != "" {
+			hr.Header.Set("X-Scope-OrgID", h.TenantID)
+		}
+		for k, v := range h.Headers {
+			hr.Header.Set(k, v)
+		}
+		return hr, nil
+	}, h.MaxRetries, h.Backoff)
+
+	if err != nil {
+		h.AddDropped(n)
+		return err
+	}
+
+	h.AddWritten(len(body))
+	return nil
+}
+
+// Flush send the pending records
+func (h *LokiHandler) Flush() error {
+	h.Lock()
+	defer h.Unlock()
+	return h.flush()
+}
+
+// Close stop the flush daemon, and send the pending records
+func (h *LokiHandler) Close() error {
+	h.Lock()
+	if h.closed {
+		h.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.Unlock()
+
+	h.flusher.Stop()
+	return h.Flush()
+}
+
+// the label name must match [a-zA-Z_][a-zA-Z0-9_]*
+func lokiLabelName(name string) string {
+	bs := []byte(name)
+	for i, c := range bs {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			bs[i] = '_'
+		}
+	}
+	return string(bs)
+}
+
+// the labels key, sorted by label name. eg: `{channel="app",level="info"}`
+func lokiLabelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name + "=" + strconv.Quote(labels[name]))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
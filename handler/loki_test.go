@@ -0,0 +1,71 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+type lokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func TestLokiHandler(t *testing.T) {
+	srv := newRecordServer(http.StatusTooManyRequests)
+	defer srv.Close()
+
+	h := handler.NewLokiHandler(srv.URL+"/loki/api/v1/push", slog.AllLevels, func(h *handler.LokiHandler) {
+		h.Labels = append(h.Labels, "app-name")
+		h.StaticLabels = map[string]string{"job": "test"}
+		h.TenantID = "team-a"
+		h.BatchSize = 4
+		h.BatchWait = time.Hour
+		h.Backoff = fastBackoff
+	})
+
+	tm := time.Unix(1614600000, 0)
+	records := []*slog.Record{
+		{Time: tm, Level: slog.InfoLevel, Channel: "web", Message: "message 1", Fields: slog.M{"app-name": "shop"}},
+		{Time: tm.Add(time.Second), Level: slog.ErrorLevel, Channel: "web", Message: "message 2"},
+		{Time: tm.Add(2 * time.Second), Level: slog.InfoLevel, Channel: "web", Message: "message 3", Fields: slog.M{"app-name": "shop"}},
+		{Time: tm.Add(3 * time.Second), Level: slog.InfoLevel, Channel: "web", Message: "message 4", Fields: slog.M{"app-name": "shop"}},
+	}
+	for _, r := range records {
+		assert.NoError(t, h.Handle(r))
+	}
+
+	// the first request is failed with 429, and retried
+	bodies := srv.Bodies()
+	assert.Len(t, bodies, 2)
+	assert.Equal(t, "team-a", srv.requests[1].Header.Get("X-Scope-OrgID"))
+
+	var push lokiPush
+	assert.NoError(t, json.Unmarshal(bodies[1], &push))
+	assert.Len(t, push.Streams, 2)
+
+	st := push.Streams[0]
+	assert.Equal(t, map[string]string{"job": "test", "channel": "web", "level": "info", "app_name": "shop"}, st.Stream)
+	assert.Len(t, st.Values, 3)
+	assert.Equal(t, "1614600000000000000", st.Values[0][0])
+	assert.Equal(t, `level=INFO message="message 1" app-name=shop`, st.Values[0][1])
+	assert.Equal(t, `level=INFO message="message 3" app-name=shop`, st.Values[1][1])
+	assert.Equal(t, `level=INFO message="message 4" app-name=shop`, st.Values[2][1])
+
+	st = push.Streams[1]
+	assert.Equal(t, map[string]string{"job": "test", "channel": "web", "level": "error"}, st.Stream)
+	assert.Equal(t, `level=ERROR message="message 2"`, st.Values[0][1])
+
+	// pending records will be sent on close
+	assert.NoError(t, h.Handle(records[0]))
+	assert.NoError(t, h.Close())
+	assert.Len(t, srv.Bodies(), 3)
+	assert.Equal(t, uint64(0), h.Dropped())
+}
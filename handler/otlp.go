@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/tomorrowsky/slog"
+)
+
+// otlpRequest the ExportLogsServiceRequest in the OTLP/JSON encoding
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource struct {
+		Attributes []slog.OTelKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpScopeLogs struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	LogRecords []*slog.OTelLogRecord `json:"logRecords"`
+}
+
+// OTLPHTTPHandler definition. export records to an OpenTelemetry collector by the OTLP/HTTP.
+//
+// - the records are mapped by the slog.OTelFormatter, and encoded as OTLP/JSON
+// - records are batched, and sent on the batch is full, or every FlushInterval
+// - the request will be retried on the network error, 429 and 5xx
+//
+// NOTICE: only support the OTLP/JSON encoding, the protobuf encoding is not supported.
+type OTLPHTTPHandler struct {
+	lockWrapper
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	pending []*slog.OTelLogRecord
+	flusher *intervalFlusher
+	closed  bool
+
+	// Levels for log message
+	Levels []slog.Level
+	// Endpoint the logs endpoint of the collector. eg: "http://localhost:4318/v1/logs"
+	Endpoint string
+	// Headers for the request. eg: auth header
+	Headers map[string]string
+	// Formatter for map the record to the OTel LogRecord
+	Formatter *slog.OTelFormatter
+	// Client for send request. default timeout is 10s
+	Client *http.Client
+	// BatchSize max records of an request. default is 100
+	BatchSize int
+	// FlushInterval for send the batched records, 0 to disable the timed flush. default is 1s
+	FlushInterval time.Duration
+	// MaxRetries for resend an request on failed. default is 3
+	MaxRetries int
+	// Backoff settings for wait before resend
+	Backoff Backoff
+}
+
+// NewOTLPHTTP create new OTLPHTTPHandler. see NewOTLPHTTPHandler()
+func NewOTLPHTTP(endpoint string, levels []slog.Level, fns ...func(h *OTLPHTTPHandler)) *OTLPHTTPHandler {
+	return NewOTLPHTTPHandler(endpoint, levels, fns...)
+}
+
+// NewOTLPHTTPHandler create new OTLPHTTPHandler, and start the flush daemon.
+//
+// Usage:
+// 	h := handler.NewOTLPHTTPHandler("http://localhost:4318/v1/logs", slog.AllLevels, func(h *handler.OTLPHTTPHandler) {
+// 		h.Formatter.Resource["service.name"] = "myapp"
+// 	})
+// 	defer h.Close()
+func NewOTLPHTTPHandler(endpoint string, levels []slog.Level, fns ...func(h *OTLPHTTPHandler)) *OTLPHTTPHandler {
+	h := &OTLPHTTPHandler{
+		Levels:   levels,
+		Endpoint: endpoint,
+		// default options
+		Formatter:     slog.NewOTelFormatter(),
+		Client:        &http.Client{Timeout: 10 * time.Second},
+		BatchSize:     100,
+		FlushInterval: time.Second,
+		MaxRetries:    3,
+		Backoff:       DefaultBackoff,
+	}
+
+	for _, fn := range fns {
+		fn(h)
+	}
+
+	h.flusher = startIntervalFlusher(h.FlushInterval, func() {
+		_ = h.Flush()
+	})
+	return h
+}
+
+// IsHandling Check if the current level can be handling
+func (h *OTLPHTTPHandler) IsHandling(level slog.Level) bool {
+	return slog.Levels(h.Levels).Contains(level)
+}
+
+// Handle log record
+func (h *OTLPHTTPHandler) Handle(r *slog.Record) error {
+	lr := h.Formatter.LogRecord(r)
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.closed {
+		return ErrHandlerClosed
+	}
+
+	h.pending = append(h.pending, lr)
+	if len(h.pending) >= h.BatchSize {
+		return h.flush()
+	}
+	return nil
+}
+
+func (h *OTLPHTTPHandler) flush() error {
+	if len(h.pending) == 0 {
+		return nil
+	}
+
+	records := h.pending
+	h.pending = nil
+
+	var req otlpRequest
+	rl := otlpResourceLogs{ScopeLogs: make([]otlpScopeLogs, 1)}
+	rl.Resource.Attributes = h.Formatter.ResourceAttributes()
+	rl.ScopeLogs[0].Scope.Name = h.Formatter.ScopeName
+	rl.ScopeLogs[0].LogRecords = records
+	req.ResourceLogs = append(req.ResourceLogs, rl)
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		h.AddDropped(len(records))
+		return err
+	}
+
+	_, err = doHTTPWithRetry(h.Client, func() (*http.Request, error) {
+		hr, err := http.NewRequest(http.MethodPost, h.Endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		hr.Header.Set("Content-Type", "application/json")
+		for k, v := range h.Headers {
+			hr.Header.Set(k, v)
+		}
+		return hr, nil
+	}, h.MaxRetries, h.Backoff)
+
+	if err != nil {
+		h.AddDropped(len(records))
+		return err
+	}
+
+	h.AddWritten(len(body))
+	return nil
+}
+
+// Flush send the pending records
+func (h *OTLPHTTPHandler) Flush() error {
+	h.Lock()
+	defer h.Unlock()
+	return h.flush()
+}
+
+// Close stop the flush daemon, and send the pending records
+func (h *OTLPHTTPHandler) Close() error {
+	h.Lock()
+	if h.closed {
+		h.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.Unlock()
+
+	h.flusher.Stop()
+	return h.Flush()
+}
@@ -0,0 +1,115 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+// recordServer an httptest server, will respond the status codes in order, then 200.
+type recordServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newRecordServer(statuses ...int) *recordServer {
+	s := &recordServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		s.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	return s
+}
+
+func (s *recordServer) Bodies() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.bodies...)
+}
+
+func TestOTLPHTTPHandler(t *testing.T) {
+	srv := newRecordServer(http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	h := handler.NewOTLPHTTPHandler(srv.URL+"/v1/logs", slog.AllLevels, func(h *handler.OTLPHTTPHandler) {
+		h.Headers = map[string]string{"Authorization": "Bearer token"}
+		h.Formatter.Resource["service.name"] = "myapp"
+		h.BatchSize = 2
+		h.FlushInterval = time.Hour
+		h.Backoff = fastBackoff
+	})
+
+	l := slog.NewWithHandlers(h)
+	l.ReportCaller = false
+	l.Info("info message")
+	assert.Len(t, srv.Bodies(), 0)
+	l.Error("error message")
+
+	// the first request is failed with 503, and retried
+	bodies := srv.Bodies()
+	assert.Len(t, bodies, 2)
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.Equal(t, "application/json", srv.requests[1].Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", srv.requests[1].Header.Get("Authorization"))
+
+	var req struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []slog.OTelKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				LogRecords []slog.OTelLogRecord `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	assert.NoError(t, json.Unmarshal(bodies[1], &req))
+
+	rl := req.ResourceLogs[0]
+	assert.Contains(t, rl.Resource.Attributes, slog.OTelKeyValue{Key: "service.name", Value: slog.OTelValue("myapp")})
+	assert.Len(t, rl.ScopeLogs[0].LogRecords, 2)
+	assert.Equal(t, 9, rl.ScopeLogs[0].LogRecords[0].SeverityNumber)
+	assert.Equal(t, "error message", *rl.ScopeLogs[0].LogRecords[1].Body.StringValue)
+
+	// pending records will be sent on close
+	l.Warn("warn message")
+	assert.NoError(t, h.Close())
+	assert.Len(t, srv.Bodies(), 3)
+}
+
+func TestOTLPHTTPHandler_error(t *testing.T) {
+	srv := newRecordServer(http.StatusBadRequest)
+	defer srv.Close()
+
+	h := handler.NewOTLPHTTPHandler(srv.URL, slog.AllLevels, func(h *handler.OTLPHTTPHandler) {
+		h.FlushInterval = 10 * time.Millisecond
+	})
+	defer h.Close()
+
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message"}))
+	time.Sleep(100 * time.Millisecond)
+
+	// 400 is not retried
+	assert.Len(t, srv.Bodies(), 1)
+	assert.Equal(t, uint64(1), h.Dropped())
+}
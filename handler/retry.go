@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/tomorrowsky/slog"
+)
+
+// ErrHandlerClosed returns on handle record after the handler closed
+var ErrHandlerClosed = errors.New("slog: the handler has been closed")
+
+// Backoff exponential backoff with jitter
+type Backoff struct {
+	// Initial wait time for the first retry. default is 100ms
+	Initial time.Duration
+	// Max wait time of an retry. default is 10s
+	Max time.Duration
+	// Multiplier of the wait time for each retry. default is 2
+	Multiplier float64
+	// Jitter factor in [0, 1]. the wait time will be randomized in [d - d*Jitter, d + d*Jitter]
+	Jitter float64
+}
+
+// DefaultBackoff settings
+var DefaultBackoff = Backoff{
+	Initial:    100 * time.Millisecond,
+	Max:        10 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Duration get the wait time for the retry attempt. attempt is start from 0.
+func (b Backoff) Duration(attempt int) time.Duration {
+	if b.Initial <= 0 {
+		b.Initial = DefaultBackoff.Initial
+	}
+	if b.Max <= 0 {
+		b.Max = DefaultBackoff.Max
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = DefaultBackoff.Multiplier
+	}
+
+	d := float64(b.Initial)
+	for i := 0; i < attempt && d < float64(b.Max); i++ {
+		d *= b.Multiplier
+	}
+
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+
+	if b.Jitter > 0 {
+		d += d * b.Jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(d)
+}
+
+// RetryHandler definition.
+// It is an wrapper of an handler, will retry to handle the record
+// with exponential backoff on the handler returns error.
+//
+// - sync mode: retry in the Handle() call, the time is bounded by MaxAttempts and MaxElapsed
+// - async mode: records are queued and handled by an background worker, the ordering is kept
+// - the permanently failing records will be sent to the DeadLetter handler
+type RetryHandler struct {
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	mu      sync.Mutex
+	handler slog.Handler
+
+	// for async mode
+	async bool
+	// sendMu guards the closed and send to queue
+	sendMu  sync.RWMutex
+	closed  bool
+	queue   chan *slog.Record
+	done    chan struct{}
+	pending int
+	idle    *sync.Cond
+
+	// MaxAttempts max handle attempts(include the first) for an record. default is 5
+	MaxAttempts int
+	// MaxElapsed max time for retry an record. 0 is no limit
+	MaxElapsed time.Duration
+	// Backoff settings for the retry wait time
+	Backoff Backoff
+	// Retryable check the error is retryable. default all errors are retryable
+	Retryable func(err error) bool
+	// DeadLetter handler for the permanently failing records
+	DeadLetter slog.Handler
+	// DropOnFull drop the new record on the queue is full, only for async mode.
+	// default will block until the queue has space.
+	DropOnFull bool
+}
+
+// NewRetry create new RetryHandler, use sync mode.
+func NewRetry(h slog.Handler) *RetryHandler {
+	return NewRetryHandler(h)
+}
+
+// NewRetryHandler create new RetryHandler, use sync mode.
+func NewRetryHandler(h slog.Handler) *RetryHandler {
+	return &RetryHandler{
+		handler:     h,
+		MaxAttempts: 5,
+		Backoff:     DefaultBackoff,
+	}
+}
+
+// NewAsyncRetryHandler create new RetryHandler with async mode.
+// queueSize is the max number of the pending records.
+//
+// Usage:
+// 	h := handler.NewAsyncRetryHandler(netHandler, 1024)
+// 	h.DeadLetter = handler.MustFileHandler("/var/log/dead-letter.log", true)
+// 	defer h.Close()
+func NewAsyncRetryHandler(h slog.Handler, queueSize int) *RetryHandler {
+	rh := NewRetryHandler(h)
+	rh.async = true
+	rh.queue = make(chan *slog.Record, queueSize)
+	rh.done = make(chan struct{})
+	rh.idle = sync.NewCond(&rh.mu)
+
+	go rh.work()
+	return rh
+}
+
+// Configure the handler
+func (h *RetryHandler) Configure(fn func(h *RetryHandler)) *RetryHandler {
+	fn(h)
+	return h
+}
+
+// IsHandling Check if the current level can be handling
+func (h *RetryHandler) IsHandling(level slog.Level) bool {
+	return h.handler.IsHandling(level)
+}
+
+// Handle log record
+func (h *RetryHandler) Handle(r *slog.Record) error {
+	if !h.async {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.handleWithRetry(r)
+	}
+
+	h.sendMu.RLock()
+	defer h.sendMu.RUnlock()
+	if h.closed {
+		return ErrHandlerClosed
+	}
+
+	h.mu.Lock()
+	h.pending++
+	h.mu.Unlock()
+
+	// the record will be reused by logger, so must use snapshot.
+	sr := r.Snapshot()
+	if h.DropOnFull {
+		select {
+		case h.queue <- sr:
+		default:
+			h.AddDropped(1)
+			h.markDone()
+		}
+		return nil
+	}
+
+	h.queue <- sr
+	return nil
+}
+
+func (h *RetryHandler) work() {
+	defer close(h.done)
+
+	for r := range h.queue {
+		_ = h.handleWithRetry(r)
+		h.markDone()
+	}
+}
+
+func (h *RetryHandler) markDone() {
+	h.mu.Lock()
+	h.pending--
+	if h.pending == 0 {
+		h.idle.Broadcast()
+	}
+	h.mu.Unlock()
+}
+
+func (h *RetryHandler) handleWithRetry(r *slog.Record) (err error) {
+	start := time.Now()
+
+	for attempt := 0; ; attempt++ {
+		if err = h.handler.Handle(r); err == nil {
+			return nil
+		}
+
+		if attempt+1 >= h.MaxAttempts || (h.Retryable != nil && !h.Retryable(err)) {
+			break
+		}
+
+		wait := h.Backoff.Duration(attempt)
+		if h.MaxElapsed > 0 && time.Since(start)+wait > h.MaxElapsed {
+			break
+		}
+		time.Sleep(wait)
+	}
+
+	// permanently failing
+	if h.DeadLetter != nil && h.DeadLetter.Handle(r) == nil {
+		return err
+	}
+
+	h.AddDropped(1)
+	return err
+}
+
+// Flush wait for all queued records are handled, then flush the handler.
+func (h *RetryHandler) Flush() error {
+	if h.async {
+		h.mu.Lock()
+		for h.pending > 0 {
+			h.idle.Wait()
+		}
+		h.mu.Unlock()
+	}
+
+	if h.DeadLetter != nil {
+		if err := h.DeadLetter.Flush(); err != nil {
+			return err
+		}
+	}
+	return h.handler.Flush()
+}
+
+// Close the handler. in async mode, will wait for all queued records are handled.
+func (h *RetryHandler) Close() error {
+	if h.async {
+		h.sendMu.Lock()
+		if !h.closed {
+			h.closed = true
+			close(h.queue)
+		}
+		h.sendMu.Unlock()
+		<-h.done
+	}
+
+	if h.DeadLetter != nil {
+		if err := h.DeadLetter.Close(); err != nil {
+			return err
+		}
+	}
+	return h.handler.Close()
+}
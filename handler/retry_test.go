@@ -0,0 +1,122 @@
+package handler_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+// flakyHandler will fail the first N calls of each record
+type flakyHandler struct {
+	*testHandler
+	mu    sync.Mutex
+	fails int
+	calls map[string]int
+}
+
+func newFlakyHandler(fails int) *flakyHandler {
+	return &flakyHandler{testHandler: newTestHandler(), fails: fails, calls: map[string]int{}}
+}
+
+func (h *flakyHandler) Handle(r *slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.calls[r.Message]++
+	if h.calls[r.Message] <= h.fails {
+		return errors.New("transient error")
+	}
+	return h.testHandler.Handle(r)
+}
+
+var fastBackoff = handler.Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond}
+
+func TestBackoff_Duration(t *testing.T) {
+	b := handler.Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	assert.Equal(t, 100*time.Millisecond, b.Duration(0))
+	assert.Equal(t, 400*time.Millisecond, b.Duration(2))
+	assert.Equal(t, time.Second, b.Duration(10))
+
+	b.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		d := b.Duration(1)
+		assert.True(t, d >= 100*time.Millisecond && d <= 300*time.Millisecond)
+	}
+}
+
+func TestRetryHandler_sync(t *testing.T) {
+	fh := newFlakyHandler(2)
+	dead := newTestHandler()
+
+	h := handler.NewRetryHandler(fh).Configure(func(h *handler.RetryHandler) {
+		h.Backoff = fastBackoff
+		h.MaxAttempts = 3
+		h.DeadLetter = dead
+	})
+
+	l := slog.NewWithHandlers(h)
+	l.ReportCaller = false
+	l.Info("message1")
+	assert.Equal(t, []string{"message1"}, fh.messages)
+	assert.Equal(t, 3, fh.calls["message1"])
+
+	// permanently failing
+	fh.fails = 10
+	l.Info("message2")
+	assert.Equal(t, 3, fh.calls["message2"])
+	assert.Equal(t, []string{"message2"}, dead.messages)
+	assert.Equal(t, uint64(0), h.Dropped())
+
+	// not retryable
+	h.Retryable = func(err error) bool { return false }
+	h.DeadLetter = nil
+	l.Info("message3")
+	assert.Equal(t, 1, fh.calls["message3"])
+	assert.Equal(t, uint64(1), h.Dropped())
+	assert.Equal(t, uint64(2), l.Stats().Errors)
+
+	assert.NoError(t, h.Flush())
+	assert.NoError(t, h.Close())
+}
+
+func TestRetryHandler_MaxElapsed(t *testing.T) {
+	fh := newFlakyHandler(100)
+	h := handler.NewRetry(fh)
+	h.MaxAttempts = 100
+	h.Backoff = handler.Backoff{Initial: 20 * time.Millisecond, Max: 20 * time.Millisecond}
+	h.MaxElapsed = 50 * time.Millisecond
+
+	start := time.Now()
+	assert.Error(t, h.Handle(&slog.Record{Message: "message"}))
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+	assert.Equal(t, 3, fh.calls["message"])
+}
+
+func TestRetryHandler_async(t *testing.T) {
+	fh := newFlakyHandler(1)
+	h := handler.NewAsyncRetryHandler(fh, 16)
+	h.Backoff = fastBackoff
+
+	l := slog.NewWithHandlers(h)
+	l.ReportCaller = false
+	for _, msg := range []string{"message1", "message2", "message3"} {
+		l.WithData(slog.M{"key": msg}).Info(msg)
+	}
+
+	assert.NoError(t, h.Flush())
+	assert.Equal(t, uint64(0), l.Stats().Errors)
+	// ordering is kept, and records are snapshot
+	assert.Equal(t, []string{"message1", "message2", "message3"}, fh.messages)
+	for i, r := range fh.records {
+		assert.Equal(t, fh.messages[i], r.Data["key"])
+		assert.Equal(t, "INFO", r.LevelName())
+	}
+
+	assert.NoError(t, h.Close())
+	assert.Equal(t, handler.ErrHandlerClosed, h.Handle(&slog.Record{Message: "closed"}))
+}
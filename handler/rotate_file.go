@@ -15,6 +15,8 @@ type RotateFileHandler struct {
 
 	// LevelsWithFormatter support limit log levels and formatter
 	LevelsWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
 
 	// for size rotating
 	written   uint64
@@ -119,6 +121,7 @@ func (h *RotateFileHandler) Handle(r *slog.Record) (err error) {
 
 	// write logs
 	n, err = h.Write(bts)
+	h.AddWritten(n)
 	if err == nil {
 		h.written += uint64(n)
 
@@ -190,5 +193,6 @@ func (h *RotateFileHandler) doRotatingFile(newFilepath string) error {
 
 	// reset h.written
 	h.written = 0
+	h.AddRotation()
 	return nil
 }
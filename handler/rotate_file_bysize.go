@@ -108,6 +108,7 @@ func (h *SizeRotateFileHandler) Handle(r *slog.Record) (err error) {
 		n, err = h.bufio.Write(bts)
 	}
 
+	h.AddWritten(n)
 	if err == nil {
 		h.written += uint64(n)
 
@@ -147,5 +148,6 @@ func (h *SizeRotateFileHandler) bySizeRotatingFile() error {
 
 	// reset h.written
 	h.written = 0
+	h.AddRotation()
 	return nil
 }
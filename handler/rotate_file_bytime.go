@@ -85,6 +85,8 @@ type TimeRotateFileHandler struct {
 
 	// LevelsWithFormatter support limit log levels and formatter
 	LevelsWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
 
 	// file *os.File
 	// baseFile string
@@ -159,7 +161,9 @@ func (h *TimeRotateFileHandler) Handle(r *slog.Record) (err error) {
 	defer h.Unlock()
 
 	// write logs
-	_, err = h.Write(bts)
+	var n int
+	n, err = h.Write(bts)
+	h.AddWritten(n)
 
 	// do rotating file
 	if err == nil {
@@ -199,5 +203,6 @@ func (h *TimeRotateFileHandler) byTimeRotatingFile() error {
 
 	// storage next rotating time
 	h.nextRotatingAt = time.Now().Unix() + h.checkInterval
+	h.AddRotation()
 	return nil
 }
@@ -27,6 +27,9 @@ func TestNewSizeRotateFileHandler(t *testing.T) {
 	}
 	l.Flush()
 	// checkLogFileContents(t, fpath)
+
+	assert.True(t, h.Rotations() > 0)
+	assert.Equal(t, h.Written(), l.Stats().Handlers["*handler.SizeRotateFileHandler"].Bytes)
 }
 
 func TestNewRotateFileHandler(t *testing.T) {
@@ -11,6 +11,8 @@ type SimpleFileHandler struct {
 	lockWrapper
 	// LevelWithFormatter support level and formatter
 	LevelWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
 }
 
 // MustSimpleFile new instance
@@ -67,6 +69,8 @@ func (h *SimpleFileHandler) Handle(r *slog.Record) (err error) {
 	defer h.Unlock()
 
 	// direct write logs
-	_, err = h.file.Write(bts)
+	var n int
+	n, err = h.file.Write(bts)
+	h.AddWritten(n)
 	return
 }
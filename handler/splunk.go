@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tomorrowsky/slog"
+)
+
+// ErrSplunkAck returns on the events are not acknowledged in the AckTimeout
+var ErrSplunkAck = errors.New("slog: splunk events are not acknowledged")
+
+// splunkEvent an event of the HEC event endpoint
+type splunkEvent struct {
+	Time       json.Number `json:"time"`
+	Host       string      `json:"host,omitempty"`
+	Source     string      `json:"source,omitempty"`
+	SourceType string      `json:"sourcetype,omitempty"`
+	Index      string      `json:"index,omitempty"`
+	Event      interface{} `json:"event"`
+}
+
+// SplunkHECHandler definition. send records to the Splunk HTTP Event Collector(HEC).
+//
+// - the records are posted to the "/services/collector/event" endpoint, with the time, host, source, sourcetype and index metadata
+// - the event is formatted by the formatter. default is slog.JSONFormatter, the JSON output is sent as an object, others as an string
+// - records are batched, and sent on the batch is full, or every FlushInterval. the body is gzip compressed by default
+// - with UseAck, it will wait for the events are acknowledged, and resend the batch on timeout(at-least-once)
+type SplunkHECHandler struct {
+	lockWrapper
+	LevelsWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	pending []splunkEvent
+
+	flusher *intervalFlusher
+	closed  bool
+
+	// URL the HEC server url. eg: "https://splunk:8088"
+	URL string
+	// Token the HEC token
+	Token string
+	// Host metadata. default is the hostname
+	Host string
+	// Source metadata. default is the Record.Channel
+	Source string
+	// SourceType metadata. eg: "_json"
+	SourceType string
+	// Index metadata. empty for use the default index of the token
+	Index string
+	// Channel the request channel(an GUID), required for the UseAck. default is an random GUID
+	Channel string
+	// UseAck wait the events are acknowledged by the ack endpoint. the indexer acknowledgment must be enabled for the token
+	UseAck bool
+	// AckTimeout max wait time for the ack. default is 30s
+	AckTimeout time.Duration
+	// AckPollInterval the interval for query the ack status. default is 1s
+	AckPollInterval time.Duration
+	// Gzip compress the request body. default is true
+	Gzip bool
+	// Headers for the request
+	Headers map[string]string
+	// Client for send request. default timeout is 10s
+	Client *http.Client
+	// BatchSize max records of an request. default is 100
+	BatchSize int
+	// FlushInterval for send the batched records, 0 to disable the timed flush. default is 1s
+	FlushInterval time.Duration
+	// MaxRetries for resend an request on failed or not acknowledged. default is 3
+	MaxRetries int
+	// Backoff settings for wait before resend
+	Backoff Backoff
+}
+
+// NewSplunkHEC create new SplunkHECHandler. see NewSplunkHECHandler()
+func NewSplunkHEC(url, token string, levels []slog.Level, fns ...func(h *SplunkHECHandler)) *SplunkHECHandler {
+	return NewSplunkHECHandler(url, token, levels, fns...)
+}
+
+// NewSplunkHECHandler create new SplunkHECHandler, and start the flush daemon.
+//
+// Usage:
+// 	h := handler.NewSplunkHECHandler("https://splunk:8088", "hec-token", slog.AllLevels, func(h *handler.SplunkHECHandler) {
+// 		h.Index = "main"
+// 		h.SourceType = "_json"
+// 		h.UseAck = true
+// 	})
+// 	defer h.Close()
+func NewSplunkHECHandler(url, token string, levels []slog.Level, fns ...func(h *SplunkHECHandler)) *SplunkHECHandler {
+	h := &SplunkHECHandler{
+		URL:   strings.TrimRight(url, "/"),
+		Token: token,
+		// init levels
+		LevelsWithFormatter: newLvsFormatter(levels),
+		// default options
+		Channel:         newGUID(),
+		AckTimeout:      30 * time.Second,
+		AckPollInterval: time.Second,
+		Gzip:            true,
+		Client:          &http.Client{Timeout: 10 * time.Second},
+		BatchSize:       100,
+		FlushInterval:   time.Second,
+		MaxRetries:      3,
+		Backoff:         DefaultBackoff,
+	}
+
+	h.Host, _ = os.Hostname()
+	h.SetFormatter(slog.NewJSONFormatter())
+
+	for _, fn := range fns {
+		fn(h)
+	}
+
+	h.flusher = startIntervalFlusher(h.FlushInterval, func() {
+		_ = h.Flush()
+	})
+	return h
+}
+
+// Handle log record
+func (h *SplunkHECHandler) Handle(r *slog.Record) error {
+	bs, err := h.Formatter().Format(r)
+	if err != nil {
+		return err
+	}
+
+	ts := r.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	ev := splunkEvent{
+		// epoch seconds, with milliseconds
+		Time:       json.Number(strconv.FormatFloat(float64(ts.UnixNano()/1e6)/1e3, 'f', 3, 64)),
+		Host:       h.Host,
+		Source:     h.Source,
+		SourceType: h.SourceType,
+		Index:      h.Index,
+	}
+	if ev.Source == "" {
+		ev.Source = r.Channel
+	}
+
+	// the JSON output is sent as an object
+	bs = bytes.TrimRight(bs, "\n")
+	if json.Valid(bs) {
+		ev.Event = json.RawMessage(append([]byte(nil), bs...))
+	} else {
+		ev.Event = string(bs)
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.closed {
+		return ErrHandlerClosed
+	}
+
+	h.pending = append(h.pending, ev)
+	if len(h.pending) >= h.BatchSize {
+		return h.flush()
+	}
+	return nil
+}
+
+func (h *SplunkHECHandler) flush() error {
+	if len(h.pending) == 0 {
+		return nil
+	}
+
+	n := len(h.pending)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, ev := range h.pending {
+		if err := enc.Encode(ev); err != nil {
+			h.pending = nil
+			h.AddDropped(n)
+			return err
+		}
+	}
+	h.pending = nil
+
+	body := buf.Bytes()
+	if h.Gzip {
+		var zb bytes.Buffer
+		zw := gzip.NewWriter(&zb)
+		_, _ = zw.Write(body)
+		_ = zw.Close()
+		body = zb.Bytes()
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := h.send(body)
+		if err == nil {
+			h.AddWritten(len(body))
+			return nil
+		}
+
+		// only resend the not acknowledged events, the request failure is retried by send()
+		if err != ErrSplunkAck || attempt >= h.MaxRetries {
+			h.AddDropped(n)
+			return err
+		}
+		time.Sleep(h.Backoff.Duration(attempt))
+	}
+}
+
+// send the events, and wait for the ack if UseAck is enabled.
+func (h *SplunkHECHandler) send(body []byte) error {
+	respBody, err := doHTTPWithRetry(h.Client, func() (*http.Request, error) {
+		req, err := h.newRequest(h.URL+"/services/collector/event", body)
+		if err != nil {
+			return nil, err
+		}
+
+		if h.Gzip {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		return req, nil
+	}, h.MaxRetries, h.Backoff)
+
+	if err != nil || !h.UseAck {
+		return err
+	}
+
+	var resp struct {
+		AckID *int64 `json:"ackId"`
+	}
+	if err = json.Unmarshal(respBody, &resp); err != nil {
+		return err
+	}
+	if resp.AckID == nil {
+		return ErrSplunkAck
+	}
+
+	return h.waitAck(*resp.AckID)
+}
+
+// query the ack status until the ackID is acknowledged, or timeout.
+func (h *SplunkHECHandler) waitAck(ackID int64) error {
+	reqBody, _ := json.Marshal(map[string][]int64{"acks": {ackID}})
+	deadline := time.Now().Add(h.AckTimeout)
+
+	for {
+		respBody, err := doHTTPWithRetry(h.Client, func() (*http.Request, error) {
+			return h.newRequest(h.URL+"/services/collector/ack", reqBody)
+		}, h.MaxRetries, h.Backoff)
+		if err != nil {
+			return err
+		}
+
+		var resp struct {
+			Acks map[string]bool `json:"acks"`
+		}
+		if err = json.Unmarshal(respBody, &resp); err != nil {
+			return err
+		}
+		if resp.Acks[strconv.FormatInt(ackID, 10)] {
+			return nil
+		}
+
+		if time.Now().Add(h.AckPollInterval).After(deadline) {
+			return ErrSplunkAck
+		}
+		time.Sleep(h.AckPollInterval)
+	}
+}
+
+func (h *SplunkHECHandler) newRequest(url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Splunk "+h.Token)
+	if h.Channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", h.Channel)
+	}
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// Flush send the pending records
+func (h *SplunkHECHandler) Flush() error {
+	h.Lock()
+	defer h.Unlock()
+	return h.flush()
+}
+
+// Close stop the flush daemon, and send the pending records
+func (h *SplunkHECHandler) Close() error {
+	h.Lock()
+	if h.closed {
+		h.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.Unlock()
+
+	h.flusher.Stop()
+	return h.Flush()
+}
+
+// new random GUID. eg: "0c5e5c4a-7c48-4a4e-9d6b-3a5f2c1e8b7d"
+func newGUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	// version 4, variant 10
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	s := hex.EncodeToString(b)
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
@@ -0,0 +1,148 @@
+package handler_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+// fakeHECServer an stand-in for the Splunk HEC. the ack will be true after pending polls
+type fakeHECServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	pending int
+	ackID   int
+	polls   int
+	headers []http.Header
+	events  []map[string]interface{}
+}
+
+func newFakeHECServer(pending int) *fakeHECServer {
+	s := &fakeHECServer{pending: pending}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		body, _ := ioutil.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/services/collector/event":
+			if r.Header.Get("Content-Encoding") == "gzip" {
+				zr, _ := gzip.NewReader(bytes.NewReader(body))
+				body, _ = ioutil.ReadAll(zr)
+			}
+
+			dec := json.NewDecoder(bytes.NewReader(body))
+			for dec.More() {
+				var ev map[string]interface{}
+				_ = dec.Decode(&ev)
+				s.events = append(s.events, ev)
+			}
+
+			s.headers = append(s.headers, r.Header)
+			s.ackID++
+			_, _ = fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d}`, s.ackID)
+		case "/services/collector/ack":
+			var req struct {
+				Acks []int `json:"acks"`
+			}
+			_ = json.Unmarshal(body, &req)
+
+			s.polls++
+			acked := s.polls > s.pending
+			_, _ = fmt.Fprintf(w, `{"acks":{"%d":%v}}`, req.Acks[0], acked)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return s
+}
+
+func (s *fakeHECServer) Events() []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]interface{}(nil), s.events...)
+}
+
+func TestSplunkHECHandler(t *testing.T) {
+	srv := newFakeHECServer(1)
+	defer srv.Close()
+
+	h := handler.NewSplunkHECHandler(srv.URL, "my-token", slog.AllLevels, func(h *handler.SplunkHECHandler) {
+		h.Host = "web-01"
+		h.SourceType = "_json"
+		h.Index = "main"
+		h.UseAck = true
+		h.AckPollInterval = 10 * time.Millisecond
+		h.BatchSize = 2
+		h.FlushInterval = time.Hour
+	})
+
+	l := slog.NewWithHandlers(h)
+	l.ReportCaller = false
+	l.Info("info message")
+	assert.Len(t, srv.Events(), 0)
+	l.Error("error message")
+
+	events := srv.Events()
+	assert.Len(t, events, 2)
+	assert.Equal(t, "web-01", events[0]["host"])
+	assert.Equal(t, "application", events[0]["source"])
+	assert.Equal(t, "_json", events[0]["sourcetype"])
+	assert.Equal(t, "main", events[0]["index"])
+	assert.IsType(t, float64(0), events[0]["time"])
+
+	ev := events[1]["event"].(map[string]interface{})
+	assert.Equal(t, "ERROR", ev["level"])
+	assert.Equal(t, "error message", ev["message"])
+
+	srv.mu.Lock()
+	assert.Equal(t, "Splunk my-token", srv.headers[0].Get("Authorization"))
+	assert.Equal(t, "gzip", srv.headers[0].Get("Content-Encoding"))
+	assert.Equal(t, h.Channel, srv.headers[0].Get("X-Splunk-Request-Channel"))
+	assert.Len(t, h.Channel, 36)
+	// polled twice for the ack
+	assert.Equal(t, 2, srv.polls)
+	srv.mu.Unlock()
+
+	// pending records will be sent on close
+	l.Warn("warn message")
+	assert.NoError(t, h.Close())
+	assert.Len(t, srv.Events(), 3)
+	assert.Equal(t, handler.ErrHandlerClosed, h.Handle(&slog.Record{Message: "message"}))
+}
+
+func TestSplunkHECHandler_ackTimeout(t *testing.T) {
+	srv := newFakeHECServer(1000)
+	defer srv.Close()
+
+	h := handler.NewSplunkHECHandler(srv.URL, "my-token", slog.AllLevels, func(h *handler.SplunkHECHandler) {
+		h.Gzip = false
+		h.UseAck = true
+		h.AckTimeout = 30 * time.Millisecond
+		h.AckPollInterval = 10 * time.Millisecond
+		h.MaxRetries = 1
+		h.Backoff = fastBackoff
+		h.SetFormatter(slog.NewTextFormatter("{{message}}"))
+	})
+	defer h.Close()
+
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message"}))
+	assert.Equal(t, handler.ErrSplunkAck, h.Flush())
+
+	// the batch is resent once on not acknowledged
+	events := srv.Events()
+	assert.Len(t, events, 2)
+	assert.Equal(t, "message", events[1]["event"])
+	assert.Equal(t, uint64(1), h.Dropped())
+}
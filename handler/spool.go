@@ -0,0 +1,555 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tomorrowsky/slog"
+)
+
+// ErrSpoolFull returns on the spool disk usage reached the SpoolHandler.MaxBytes
+var ErrSpoolFull = errors.New("slog: the spool is full")
+
+const (
+	spoolSegmentExt  = ".seg"
+	spoolCursorFile  = "cursor"
+	spoolSegmentName = "%012d" + spoolSegmentExt
+)
+
+// spoolRecord the serialized record in the spool segment
+type spoolRecord struct {
+	Time    time.Time    `json:"time"`
+	Level   slog.Level   `json:"level"`
+	Channel string       `json:"channel"`
+	Message string       `json:"message"`
+	Data    slog.M       `json:"data,omitempty"`
+	Extra   slog.M       `json:"extra,omitempty"`
+	Fields  slog.M       `json:"fields,omitempty"`
+	Caller  *spoolCaller `json:"caller,omitempty"`
+}
+
+type spoolCaller struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Function string `json:"func"`
+}
+
+func encodeSpoolRecord(r *slog.Record) ([]byte, error) {
+	sr := spoolRecord{
+		Time:    r.Time,
+		Level:   r.Level,
+		Channel: r.Channel,
+		Message: r.Message,
+		Data:    r.Data,
+		Extra:   r.Extra,
+		Fields:  r.Fields,
+	}
+	if r.Caller != nil {
+		sr.Caller = &spoolCaller{File: r.Caller.File, Line: r.Caller.Line, Function: r.Caller.Function}
+	}
+
+	bts, err := json.Marshal(sr)
+	if err != nil {
+		return nil, err
+	}
+	return append(bts, '\n'), nil
+}
+
+func decodeSpoolRecord(line []byte) (*slog.Record, error) {
+	var sr spoolRecord
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&sr); err != nil {
+		return nil, err
+	}
+
+	r := &slog.Record{
+		Time:    sr.Time,
+		Level:   sr.Level,
+		Channel: sr.Channel,
+		Message: sr.Message,
+		Data:    sr.Data,
+		Extra:   sr.Extra,
+		Fields:  sr.Fields,
+	}
+	if sr.Caller != nil {
+		r.Caller = &runtime.Frame{File: sr.Caller.File, Line: sr.Caller.Line, Function: sr.Caller.Function}
+	}
+	return r, nil
+}
+
+// spoolCursor the delivered position
+type spoolCursor struct {
+	Segment int64 `json:"segment"`
+	Offset  int64 `json:"offset"`
+}
+
+// SpoolHandler definition. an durable store-and-forward handler.
+//
+// - records are serialized and appended to the segment files in the Dir
+// - an background forwarder delivers the records to the downstream handler in order
+// - delivered position is saved to the cursor file, and delivered segments will be deleted
+// - the undelivered records will be delivered continue after the process restarts
+//
+// NOTICE: delivery is at-least-once, some records may be delivered again after an crash.
+// The numbers in the Data, Extra, Fields will be decoded as json.Number.
+type SpoolHandler struct {
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	mu   sync.Mutex
+	dir  string
+	next slog.Handler
+
+	// segment indexes, sorted
+	segments []int64
+	// bytes size of all segments
+	totalBytes int64
+	// current write segment
+	writeFile *os.File
+	writeSize int64
+	// the delivered position, update by forwarder
+	cursor spoolCursor
+
+	closed  bool
+	notify  chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+
+	// SegmentSize max bytes size of an segment file. default is 16MB
+	SegmentSize int64
+	// MaxBytes max bytes size of the undelivered records. 0 is no limit.
+	// will return ErrSpoolFull for the new records after reached.
+	// the disk usage can be up to MaxBytes + SegmentSize, the delivered segments are deleted.
+	MaxBytes int64
+	// SyncWrite call fsync after each write
+	SyncWrite bool
+	// AckEvery save the cursor after every N records delivered. default is 100
+	AckEvery int
+	// PollInterval for check new records. default is 1s
+	PollInterval time.Duration
+	// Backoff settings for retry deliver an record to the downstream
+	Backoff Backoff
+}
+
+// NewSpool create new SpoolHandler. see NewSpoolHandler()
+func NewSpool(dir string, downstream slog.Handler, fns ...func(h *SpoolHandler)) (*SpoolHandler, error) {
+	return NewSpoolHandler(dir, downstream, fns...)
+}
+
+// NewSpoolHandler create new SpoolHandler, and start the forwarder.
+//
+// Usage:
+// 	h, err := handler.NewSpoolHandler("/var/spool/myapp", netHandler, func(h *handler.SpoolHandler) {
+// 		h.MaxBytes = 1 << 30
+// 	})
+// 	defer h.Close()
+func NewSpoolHandler(dir string, downstream slog.Handler, fns ...func(h *SpoolHandler)) (*SpoolHandler, error) {
+	h := &SpoolHandler{
+		dir:     dir,
+		next:    downstream,
+		notify:  make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+		// default options
+		SegmentSize:  16 * 1024 * 1024,
+		AckEvery:     100,
+		PollInterval: time.Second,
+		Backoff:      DefaultBackoff,
+	}
+
+	for _, fn := range fns {
+		fn(h)
+	}
+
+	if err := h.open(); err != nil {
+		return nil, err
+	}
+
+	go h.forward()
+	return h, nil
+}
+
+// open the spool dir, load segments and cursor
+func (h *SpoolHandler) open() error {
+	if err := os.MkdirAll(h.dir, 0777); err != nil {
+		return err
+	}
+
+	files, err := ioutil.ReadDir(h.dir)
+	if err != nil {
+		return err
+	}
+
+	// load cursor
+	if bts, err := ioutil.ReadFile(h.path(spoolCursorFile)); err == nil {
+		if err := json.Unmarshal(bts, &h.cursor); err != nil {
+			return fmt.Errorf("slog: invalid spool cursor file: %v", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	var lastSize int64
+	for _, fi := range files {
+		var idx int64
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), spoolSegmentExt) {
+			continue
+		}
+		if _, err := fmt.Sscanf(fi.Name(), spoolSegmentName, &idx); err != nil {
+			continue
+		}
+
+		// has been delivered
+		if idx < h.cursor.Segment {
+			_ = os.Remove(h.path(fi.Name()))
+			continue
+		}
+
+		// the files are sorted by name
+		h.segments = append(h.segments, idx)
+		h.totalBytes += fi.Size()
+		lastSize = fi.Size()
+	}
+
+	if len(h.segments) == 0 {
+		h.cursor.Offset = 0
+		return h.newSegment(h.cursor.Segment)
+	}
+
+	// the cursor segment has been deleted
+	if h.segments[0] > h.cursor.Segment {
+		h.cursor = spoolCursor{Segment: h.segments[0]}
+	}
+
+	last := h.segments[len(h.segments)-1]
+	if lastSize > 0 && !endsWithNewline(h.segmentPath(last), lastSize) {
+		// the last record is incomplete, start an new segment
+		return h.newSegment(last + 1)
+	}
+
+	file, err := OpenFile(h.segmentPath(last), DefaultFileFlags, DefaultFilePerm)
+	if err != nil {
+		return err
+	}
+
+	h.writeFile = file
+	h.writeSize = lastSize
+	return nil
+}
+
+func endsWithNewline(fpath string, size int64) bool {
+	file, err := os.Open(fpath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	b := make([]byte, 1)
+	if _, err = file.ReadAt(b, size-1); err != nil {
+		return false
+	}
+	return b[0] == '\n'
+}
+
+func (h *SpoolHandler) newSegment(idx int64) error {
+	file, err := OpenFile(h.segmentPath(idx), DefaultFileFlags, DefaultFilePerm)
+	if err != nil {
+		return err
+	}
+
+	if h.writeFile != nil {
+		_ = h.writeFile.Close()
+	}
+
+	h.writeFile = file
+	h.writeSize = 0
+	h.segments = append(h.segments, idx)
+	return nil
+}
+
+func (h *SpoolHandler) path(name string) string {
+	return filepath.Join(h.dir, name)
+}
+
+func (h *SpoolHandler) segmentPath(idx int64) string {
+	return h.path(fmt.Sprintf(spoolSegmentName, idx))
+}
+
+func (h *SpoolHandler) writeSegment() int64 {
+	return h.segments[len(h.segments)-1]
+}
+
+// IsHandling Check if the current level can be handling
+func (h *SpoolHandler) IsHandling(level slog.Level) bool {
+	return h.next.IsHandling(level)
+}
+
+// Handle log record, will append the record to spool.
+func (h *SpoolHandler) Handle(r *slog.Record) error {
+	line, err := encodeSpoolRecord(r)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrHandlerClosed
+	}
+
+	// the delivered bytes of the cursor segment are not counted
+	if h.MaxBytes > 0 && h.totalBytes-h.cursor.Offset+int64(len(line)) > h.MaxBytes {
+		h.AddDropped(1)
+		return ErrSpoolFull
+	}
+
+	if h.writeSize > 0 && h.writeSize+int64(len(line)) > h.SegmentSize {
+		if err := h.newSegment(h.writeSegment() + 1); err != nil {
+			return err
+		}
+	}
+
+	n, err := h.writeFile.Write(line)
+	h.writeSize += int64(n)
+	h.totalBytes += int64(n)
+	h.AddWritten(n)
+	if err != nil {
+		return err
+	}
+
+	if h.SyncWrite {
+		if err := h.writeFile.Sync(); err != nil {
+			return err
+		}
+	}
+
+	// notify the forwarder
+	select {
+	case h.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Backlog bytes size of the undelivered records
+func (h *SpoolHandler) Backlog() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.totalBytes - h.cursor.Offset
+}
+
+// Segments number of the spool
+func (h *SpoolHandler) Segments() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.segments)
+}
+
+// forwarder: deliver the records to the downstream handler in order
+func (h *SpoolHandler) forward() {
+	defer close(h.done)
+
+	var (
+		file   *os.File
+		reader *bufio.Reader
+		acked  int
+	)
+
+	closeFile := func() {
+		if file != nil {
+			_ = file.Close()
+			file, reader = nil, nil
+		}
+	}
+	defer closeFile()
+
+	for {
+		h.mu.Lock()
+		cur := h.cursor
+		isWriting := cur.Segment == h.writeSegment()
+		h.mu.Unlock()
+
+		if file == nil {
+			var err error
+			if file, err = os.Open(h.segmentPath(cur.Segment)); err != nil {
+				if !h.wait() {
+					return
+				}
+				continue
+			}
+
+			if _, err = file.Seek(cur.Offset, io.SeekStart); err != nil {
+				closeFile()
+				if !h.wait() {
+					return
+				}
+				continue
+			}
+			reader = bufio.NewReader(file)
+		}
+
+		line, err := reader.ReadBytes('\n')
+		if err == nil {
+			if !h.deliver(line) {
+				return
+			}
+
+			h.mu.Lock()
+			h.cursor.Offset += int64(len(line))
+			h.mu.Unlock()
+
+			if acked++; acked >= h.AckEvery {
+				acked = 0
+				h.saveCursor()
+			}
+			continue
+		}
+
+		// reached the end of the segment
+		if isWriting {
+			// the partial line will be read again
+			if len(line) > 0 {
+				closeFile()
+			}
+
+			if acked > 0 {
+				acked = 0
+				h.saveCursor()
+			}
+
+			if !h.wait() {
+				return
+			}
+			continue
+		}
+
+		// an delivered segment, delete it and move to next
+		if len(line) > 0 {
+			h.AddDropped(1) // incomplete record
+		}
+
+		closeFile()
+		h.removeSegment(cur.Segment)
+		acked = 0
+		h.saveCursor()
+	}
+}
+
+// deliver an line to the downstream, will retry until success or closed.
+func (h *SpoolHandler) deliver(line []byte) bool {
+	r, err := decodeSpoolRecord(line)
+	if err != nil {
+		// invalid record, skip it
+		h.AddDropped(1)
+		return true
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err = h.next.Handle(r); err == nil {
+			return true
+		}
+
+		select {
+		case <-h.closing:
+			return false
+		case <-time.After(h.Backoff.Duration(attempt)):
+		}
+	}
+}
+
+// wait for new records. return false on closing.
+func (h *SpoolHandler) wait() bool {
+	select {
+	case <-h.closing:
+		return false
+	case <-h.notify:
+	case <-time.After(h.PollInterval):
+	}
+	return true
+}
+
+func (h *SpoolHandler) removeSegment(idx int64) {
+	fpath := h.segmentPath(idx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if fi, err := os.Stat(fpath); err == nil {
+		h.totalBytes -= fi.Size()
+	}
+	_ = os.Remove(fpath)
+
+	h.segments = h.segments[1:]
+	h.cursor = spoolCursor{Segment: h.segments[0]}
+}
+
+func (h *SpoolHandler) saveCursor() {
+	h.mu.Lock()
+	bts, _ := json.Marshal(h.cursor)
+	h.mu.Unlock()
+
+	tmp := h.path(spoolCursorFile + ".tmp")
+	if err := ioutil.WriteFile(tmp, bts, os.FileMode(DefaultFilePerm)); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, h.path(spoolCursorFile))
+}
+
+// WaitDrained wait for all spooled records are delivered.
+// return false on timeout.
+func (h *SpoolHandler) WaitDrained(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for h.Backlog() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+// Flush sync the spool segment to disk, and flush the downstream handler
+func (h *SpoolHandler) Flush() error {
+	h.mu.Lock()
+	err := h.writeFile.Sync()
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return h.next.Flush()
+}
+
+// Close the handler. will stop the forwarder and save the cursor.
+// the undelivered records will be kept in the spool.
+func (h *SpoolHandler) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.mu.Unlock()
+
+	close(h.closing)
+	<-h.done
+	h.saveCursor()
+
+	if err := h.writeFile.Close(); err != nil {
+		return err
+	}
+	return h.next.Close()
+}
@@ -0,0 +1,130 @@
+package handler_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+func newTestSpool(t *testing.T, dir string, next slog.Handler, fns ...func(h *handler.SpoolHandler)) *handler.SpoolHandler {
+	fns = append([]func(h *handler.SpoolHandler){func(h *handler.SpoolHandler) {
+		h.Backoff = fastBackoff
+		h.PollInterval = 10 * time.Millisecond
+	}}, fns...)
+
+	h, err := handler.NewSpoolHandler(dir, next, fns...)
+	assert.NoError(t, err)
+	return h
+}
+
+func (h *flakyHandler) Messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.messages...)
+}
+
+func TestSpoolHandler(t *testing.T) {
+	dir := "./testdata/spool"
+	assert.NoError(t, os.RemoveAll(dir))
+
+	next := newFlakyHandler(2)
+	h := newTestSpool(t, dir, next, func(h *handler.SpoolHandler) {
+		h.SegmentSize = 256
+	})
+
+	l := slog.NewWithHandlers(h)
+	l.ReportCaller = false
+	l.WithData(slog.M{"id": 1}).Info("message 1")
+	l.Warn("message 2")
+	l.Error("message 3")
+
+	assert.True(t, h.WaitDrained(3*time.Second))
+	assert.Equal(t, int64(0), h.Backlog())
+	assert.Equal(t, []string{"message 1", "message 2", "message 3"}, next.Messages())
+	assert.Equal(t, 1, h.Segments())
+
+	r := next.records[0]
+	assert.Equal(t, slog.InfoLevel, r.Level)
+	assert.Equal(t, "1", fmt.Sprint(r.Data["id"]))
+	assert.Equal(t, slog.WarnLevel, next.records[1].Level)
+
+	assert.NoError(t, h.Close())
+	assert.Equal(t, handler.ErrHandlerClosed, h.Handle(&slog.Record{Message: "message"}))
+}
+
+func TestSpoolHandler_restart(t *testing.T) {
+	dir := "./testdata/spool-restart"
+	assert.NoError(t, os.RemoveAll(dir))
+
+	// downstream is unreachable
+	h := newTestSpool(t, dir, newFlakyHandler(1000))
+	for _, msg := range []string{"message 1", "message 2", "message 3"} {
+		assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: msg}))
+	}
+
+	assert.True(t, h.Backlog() > 0)
+	assert.NoError(t, h.Close())
+
+	// restart
+	next := newFlakyHandler(0)
+	h = newTestSpool(t, dir, next)
+	assert.True(t, h.Backlog() > 0)
+	assert.True(t, h.WaitDrained(3*time.Second))
+	assert.Equal(t, []string{"message 1", "message 2", "message 3"}, next.Messages())
+	assert.NoError(t, h.Close())
+
+	// will not deliver again
+	next = newFlakyHandler(0)
+	h = newTestSpool(t, dir, next)
+	assert.Equal(t, int64(0), h.Backlog())
+	assert.NoError(t, h.Close())
+	assert.Len(t, next.Messages(), 0)
+}
+
+func TestSpoolHandler_MaxBytes(t *testing.T) {
+	dir := "./testdata/spool-full"
+	assert.NoError(t, os.RemoveAll(dir))
+
+	h := newTestSpool(t, dir, newFlakyHandler(1000), func(h *handler.SpoolHandler) {
+		h.MaxBytes = 200
+	})
+	defer h.Close()
+
+	r := &slog.Record{Level: slog.InfoLevel, Message: "message"}
+
+	var err error
+	for i := 0; i < 5 && err == nil; i++ {
+		err = h.Handle(r)
+	}
+
+	assert.Equal(t, handler.ErrSpoolFull, err)
+	assert.Equal(t, uint64(1), h.Dropped())
+	assert.True(t, h.Backlog() <= 200)
+}
+
+// the delivered records are not counted to the MaxBytes
+func TestSpoolHandler_MaxBytes_delivered(t *testing.T) {
+	dir := "./testdata/spool-delivered"
+	assert.NoError(t, os.RemoveAll(dir))
+
+	next := newFlakyHandler(0)
+	h := newTestSpool(t, dir, next, func(h *handler.SpoolHandler) {
+		h.MaxBytes = 1000
+	})
+	defer h.Close()
+
+	r := &slog.Record{Level: slog.InfoLevel, Message: "message"}
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, h.Handle(r))
+		assert.True(t, h.WaitDrained(3*time.Second))
+	}
+
+	assert.Len(t, next.Messages(), 50)
+	assert.Equal(t, uint64(0), h.Dropped())
+	assert.Equal(t, int64(0), h.Backlog())
+}
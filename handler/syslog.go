@@ -13,6 +13,7 @@ import (
 type SysLogHandler struct {
 	slWriter *syslog.Writer
 	LevelWithFormatter
+	slog.HandlerMetrics
 }
 
 // NewSysLogHandler instance
@@ -38,7 +39,29 @@ func (h *SysLogHandler) Handle(record *slog.Record) error {
 		return err
 	}
 
-	return h.slWriter.Info(string(bts))
+	if err = h.write(record.Level, string(bts)); err == nil {
+		h.AddWritten(len(bts))
+	}
+	return err
+}
+
+// write message by the syslog severity of the level
+func (h *SysLogHandler) write(level slog.Level, msg string) error {
+	switch level.SyslogSeverity() {
+	case 0:
+		return h.slWriter.Emerg(msg)
+	case 2:
+		return h.slWriter.Crit(msg)
+	case 3:
+		return h.slWriter.Err(msg)
+	case 4:
+		return h.slWriter.Warning(msg)
+	case 5:
+		return h.slWriter.Notice(msg)
+	case 6:
+		return h.slWriter.Info(msg)
+	}
+	return h.slWriter.Debug(msg)
 }
 
 func (h *SysLogHandler) Close() error {
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/tomorrowsky/slog"
+)
+
+// RemoteSyslogHandler definition. send RFC 5424 syslog messages to an remote server.
+//
+// - "udp": one message per datagram
+// - "tcp", "tls": the messages are framed by the octet-counting(RFC 6587)
+//
+// On write failed, it will reconnect and resend the message once.
+type RemoteSyslogHandler struct {
+	lockWrapper
+	LevelsWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	network string
+	addr    string
+	conn    net.Conn
+
+	// TLSConfig for the "tls" network
+	TLSConfig *tls.Config
+	// DialTimeout for connect the server. default is 5s
+	DialTimeout time.Duration
+	// WriteTimeout for send an message. default is 5s
+	WriteTimeout time.Duration
+}
+
+// NewRemoteSyslog create new RemoteSyslogHandler. see NewRemoteSyslogHandler()
+func NewRemoteSyslog(network, addr string, levels []slog.Level) (*RemoteSyslogHandler, error) {
+	return NewRemoteSyslogHandler(network, addr, levels)
+}
+
+// NewRemoteSyslogHandler create new RemoteSyslogHandler. network allow: "udp", "tcp", "tls"
+//
+// Usage:
+// 	h, err := handler.NewRemoteSyslogHandler("tcp", "syslog:514", slog.AllLevels)
+func NewRemoteSyslogHandler(network, addr string, levels []slog.Level) (*RemoteSyslogHandler, error) {
+	return NewRemoteSyslogWithTLS(network, addr, levels, nil)
+}
+
+// NewRemoteSyslogWithTLS create new RemoteSyslogHandler with the TLS config for the "tls" network
+//
+// Usage:
+// 	h, err := handler.NewRemoteSyslogWithTLS("tls", "syslog:6514", slog.AllLevels, &tls.Config{})
+func NewRemoteSyslogWithTLS(network, addr string, levels []slog.Level, tlsConf *tls.Config) (*RemoteSyslogHandler, error) {
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "tls":
+	default:
+		return nil, errors.New("slog: unsupported syslog network " + network)
+	}
+
+	h := &RemoteSyslogHandler{
+		network: network,
+		addr:    addr,
+		// init levels
+		LevelsWithFormatter: newLvsFormatter(levels),
+		// default options
+		TLSConfig:    tlsConf,
+		DialTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+
+	h.SetFormatter(slog.NewSyslogFormatter())
+
+	if err := h.connect(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Configure the handler
+func (h *RemoteSyslogHandler) Configure(fn func(h *RemoteSyslogHandler)) *RemoteSyslogHandler {
+	fn(h)
+	return h
+}
+
+func (h *RemoteSyslogHandler) connect() (err error) {
+	if h.network == "tls" {
+		dialer := &net.Dialer{Timeout: h.DialTimeout}
+		h.conn, err = tls.DialWithDialer(dialer, "tcp", h.addr, h.TLSConfig)
+		return
+	}
+
+	h.conn, err = net.DialTimeout(h.network, h.addr, h.DialTimeout)
+	return
+}
+
+// Handle log record
+func (h *RemoteSyslogHandler) Handle(r *slog.Record) error {
+	bts, err := h.Formatter().Format(r)
+	if err != nil {
+		return err
+	}
+
+	// octet-counting: MSG-LEN SP SYSLOG-MSG
+	if h.network[:3] != "udp" {
+		bts = append([]byte(strconv.Itoa(len(bts))+" "), bts...)
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if err = h.write(bts); err != nil {
+		return err
+	}
+
+	h.AddWritten(len(bts))
+	return nil
+}
+
+// write message, will reconnect and resend once on failed.
+func (h *RemoteSyslogHandler) write(bts []byte) (err error) {
+	if h.conn == nil {
+		if err = h.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err = h.writeConn(bts); err == nil {
+		return nil
+	}
+
+	_ = h.conn.Close()
+	if err = h.connect(); err != nil {
+		h.conn = nil
+		return err
+	}
+	return h.writeConn(bts)
+}
+
+func (h *RemoteSyslogHandler) writeConn(bts []byte) error {
+	if h.WriteTimeout > 0 {
+		_ = h.conn.SetWriteDeadline(time.Now().Add(h.WriteTimeout))
+	}
+
+	_, err := h.conn.Write(bts)
+	return err
+}
+
+// Flush no operation
+func (h *RemoteSyslogHandler) Flush() error {
+	return nil
+}
+
+// Close the connection
+func (h *RemoteSyslogHandler) Close() error {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.conn == nil {
+		return nil
+	}
+
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
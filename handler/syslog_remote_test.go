@@ -0,0 +1,138 @@
+package handler_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+// read octet-counting framed messages. the connection will be closed after read maxMsg messages.
+func serveSyslog(ln net.Listener, msgs chan<- string, maxMsg int) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for i := 0; maxMsg <= 0 || i < maxMsg; i++ {
+				size, err := r.ReadString(' ')
+				if err != nil {
+					return
+				}
+
+				n, _ := strconv.Atoi(strings.TrimSpace(size))
+				buf := make([]byte, n)
+				if _, err = io.ReadFull(r, buf); err != nil {
+					return
+				}
+				msgs <- string(buf)
+			}
+		}(conn)
+	}
+}
+
+func waitSyslogMsg(t *testing.T, msgs <-chan string, contains string) string {
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case msg := <-msgs:
+			if strings.Contains(msg, contains) {
+				return msg
+			}
+		case <-timeout:
+			t.Fatal("timeout for receive the message: " + contains)
+			return ""
+		}
+	}
+}
+
+func newSyslogRecord(level slog.Level, msg string) *slog.Record {
+	return &slog.Record{
+		Time:    time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+		Level:   level,
+		Channel: "app",
+		Message: msg,
+		Fields:  slog.M{"uid": 23, "path": `a"b]c`},
+	}
+}
+
+func TestRemoteSyslogHandler_udp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	h, err := handler.NewRemoteSyslogHandler("udp", conn.LocalAddr().String(), slog.AllLevels)
+	assert.NoError(t, err)
+	defer h.Close()
+
+	h.SetFormatter(slog.NewSyslogFormatter(func(f *slog.SyslogFormatter) {
+		f.Hostname = "myhost"
+		f.AppName = "myapp"
+		f.ProcID = "1234"
+	}))
+	assert.NoError(t, h.Handle(newSyslogRecord(slog.ErrorLevel, "error message")))
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, `<11>1 2021-03-01T12:00:00.000000Z myhost myapp 1234 app [fields@32473 path="a\"b\]c" uid="23"] error message`, string(buf[:n]))
+}
+
+func TestRemoteSyslogHandler_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	msgs := make(chan string, 10)
+	// the first connection will be closed after read one message
+	go serveSyslog(ln, msgs, 1)
+
+	h, err := handler.NewRemoteSyslogHandler("tcp", ln.Addr().String(), slog.AllLevels)
+	assert.NoError(t, err)
+	defer h.Close()
+
+	assert.NoError(t, h.Handle(newSyslogRecord(slog.WarnLevel, "message 1")))
+	assert.True(t, strings.HasPrefix(waitSyslogMsg(t, msgs, "message 1"), "<12>1 "))
+
+	// reconnect after the server closed the connection
+	time.Sleep(50 * time.Millisecond)
+	_ = h.Handle(newSyslogRecord(slog.InfoLevel, "message 2"))
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, h.Handle(newSyslogRecord(slog.InfoLevel, "message 3")))
+	assert.True(t, strings.HasPrefix(waitSyslogMsg(t, msgs, "message 3"), "<14>1 "))
+}
+
+func TestRemoteSyslogHandler_tls(t *testing.T) {
+	// use the test certificate of the httptest
+	srv := httptest.NewTLSServer(nil)
+	cert := srv.TLS.Certificates[0]
+	srv.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	msgs := make(chan string, 10)
+	go serveSyslog(ln, msgs, 0)
+
+	h, err := handler.NewRemoteSyslogWithTLS("tls", ln.Addr().String(), slog.AllLevels, &tls.Config{InsecureSkipVerify: true})
+	assert.NoError(t, err)
+	defer h.Close()
+
+	assert.NoError(t, h.Handle(newSyslogRecord(slog.DebugLevel, "debug message")))
+	assert.True(t, strings.HasPrefix(waitSyslogMsg(t, msgs, "debug message"), "<15>1 "))
+}
@@ -0,0 +1,374 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tomorrowsky/slog"
+)
+
+// built-in payload templates for the WebhookHandler
+const (
+	// WebhookPresetSlack the Slack incoming webhook payload
+	WebhookPresetSlack = `{"text": {{ json (truncate 39000 .Text) }}}`
+	// WebhookPresetDiscord the Discord webhook payload. the content max length is 2000
+	WebhookPresetDiscord = `{"content": {{ json (truncate 2000 .Text) }}}`
+	// WebhookPresetTeams the Microsoft Teams incoming webhook payload(MessageCard)
+	WebhookPresetTeams = `{"@type": "MessageCard", "@context": "http://schema.org/extensions", ` +
+		`"summary": {{ json .Title }}, "title": {{ json .Title }}, "text": {{ json (truncate 20000 .Text) }}}`
+)
+
+// WebhookRecord an aggregated record for render the payload
+type WebhookRecord struct {
+	Time    time.Time
+	Level   slog.Level
+	Channel string
+	Message string
+	// Text the record formatted by the handler formatter
+	Text string
+	// Count of the same records(level, channel and message) in the window
+	Count int
+}
+
+// WebhookMessage the data for render the payload template
+type WebhookMessage struct {
+	// Title eg: "[ERROR] 3 log records"
+	Title string
+	// Level name of the highest level in the records
+	Level   string
+	Records []*WebhookRecord
+	// Text the texts of the records, joined by newline. the repeated records has suffix " (xN)"
+	Text string
+	// Total count of the records, include the repeated
+	Total int
+	// Dropped count of the records dropped by the MaxPending since last message
+	Dropped int
+}
+
+// NewWebhookTemplate parse the payload template, with the template funcs:
+//
+// - json: encode the value as JSON. eg: {{ json .Text }}
+// - truncate: truncate the string to max bytes, with the suffix "...". eg: {{ truncate 2000 .Text }}
+//
+// Usage:
+// 	tpl := template.Must(handler.NewWebhookTemplate(`{"msg": {{ json .Title }}, "count": {{ .Total }}}`))
+func NewWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			bs, err := json.Marshal(v)
+			return string(bs), err
+		},
+		"truncate": truncateString,
+	}).Parse(text)
+}
+
+// truncate the string to max n bytes, and not break the UTF-8 chars.
+// the suffix "..." is added on the n > 3.
+func truncateString(n int, s string) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+
+	suffix := "..."
+	if n <= len(suffix) {
+		suffix = ""
+	}
+
+	cut := n - len(suffix)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + suffix
+}
+
+// WebhookHandler definition. post records to an webhook, eg: Slack, Microsoft Teams, Discord.
+//
+// - records are aggregated in the Window, the same records(level, channel and message) are merged with an count
+// - the payload is rendered by the Template with an WebhookMessage. default is the Slack preset
+// - the messages are limited by RateLimit in RatePeriod, the records are kept to the next window on limited
+type WebhookHandler struct {
+	lockWrapper
+	LevelsWithFormatter
+	// HandlerMetrics report metrics to the logger stats
+	slog.HandlerMetrics
+
+	records []*WebhookRecord
+	index   map[string]*WebhookRecord
+	dropped int
+	// the send time of the messages in the RatePeriod
+	sent []time.Time
+
+	flusher *intervalFlusher
+	closed  bool
+
+	// URL the webhook url
+	URL string
+	// Template for render the payload. default is WebhookPresetSlack
+	Template *template.Template
+	// ContentType of the payload. default is "application/json"
+	ContentType string
+	// Headers for the request
+	Headers map[string]string
+	// TLSConfig for the https request, will be used on the Client.Transport is nil
+	TLSConfig *tls.Config
+	// Client for send request. default timeout is 10s
+	Client *http.Client
+	// Window for aggregate the records into an message, 0 to send each record immediately. default is 10s
+	Window time.Duration
+	// MaxBatch max records(not include the repeated) of an message, will send immediately on reached. default is 20
+	MaxBatch int
+	// MaxPending max pending records on rate limited, the others will be dropped. default is 1000
+	MaxPending int
+	// RateLimit max messages in the RatePeriod, 0 is unlimited. default is 0
+	RateLimit int
+	// RatePeriod for the RateLimit. default is 1 minute
+	RatePeriod time.Duration
+	// MaxRetries for resend an request on failed. default is 3
+	MaxRetries int
+	// Backoff settings for wait before resend
+	Backoff Backoff
+}
+
+// NewWebhook create new WebhookHandler. see NewWebhookHandler()
+func NewWebhook(url string, levels []slog.Level, fns ...func(h *WebhookHandler)) *WebhookHandler {
+	return NewWebhookHandler(url, levels, fns...)
+}
+
+// NewWebhookHandler create new WebhookHandler, and start the flush daemon.
+//
+// Usage:
+// 	h := handler.NewWebhookHandler("https://hooks.slack.com/services/xxx", slog.DangerLevels, func(h *handler.WebhookHandler) {
+// 		h.RateLimit = 10
+// 	})
+// 	defer h.Close()
+//
+// 	// use the Discord preset
+// 	h.Template = template.Must(handler.NewWebhookTemplate(handler.WebhookPresetDiscord))
+func NewWebhookHandler(url string, levels []slog.Level, fns ...func(h *WebhookHandler)) *WebhookHandler {
+	h := &WebhookHandler{
+		URL:   url,
+		index: make(map[string]*WebhookRecord),
+		// init levels
+		LevelsWithFormatter: newLvsFormatter(levels),
+		// default options
+		Template:    template.Must(NewWebhookTemplate(WebhookPresetSlack)),
+		ContentType: "application/json",
+		Window:      10 * time.Second,
+		MaxBatch:    20,
+		MaxPending:  1000,
+		RatePeriod:  time.Minute,
+		MaxRetries:  3,
+		Backoff:     DefaultBackoff,
+	}
+
+	h.SetFormatter(slog.NewTextFormatter("[{{level}}] [{{channel}}] {{message}} {{data}} {{extra}}"))
+
+	for _, fn := range fns {
+		fn(h)
+	}
+
+	if h.Client == nil {
+		h.Client = &http.Client{Timeout: 10 * time.Second}
+		if h.TLSConfig != nil {
+			h.Client.Transport = &http.Transport{TLSClientConfig: h.TLSConfig}
+		}
+	}
+
+	h.flusher = startIntervalFlusher(h.Window, func() {
+		h.Lock()
+		defer h.Unlock()
+		_ = h.flush(false)
+	})
+	return h
+}
+
+// Handle log record
+func (h *WebhookHandler) Handle(r *slog.Record) error {
+	bs, err := h.Formatter().Format(r)
+	if err != nil {
+		return err
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	if h.closed {
+		return ErrHandlerClosed
+	}
+
+	key := webhookRecordKey(r.Level, r.Channel, r.Message)
+	if wr, ok := h.index[key]; ok {
+		wr.Count++
+		return nil
+	}
+
+	if len(h.records) >= h.MaxPending {
+		h.dropped++
+		h.AddDropped(1)
+		return nil
+	}
+
+	wr := &WebhookRecord{
+		Time:    r.Time,
+		Level:   r.Level,
+		Channel: r.Channel,
+		Message: r.Message,
+		Text:    strings.TrimRight(string(bs), " \n"),
+		Count:   1,
+	}
+	if wr.Time.IsZero() {
+		wr.Time = time.Now()
+	}
+
+	h.records = append(h.records, wr)
+	h.index[key] = wr
+	if h.Window <= 0 || len(h.records) >= h.MaxBatch {
+		return h.flush(false)
+	}
+	return nil
+}
+
+// build the message by the records
+func (h *WebhookHandler) message(records []*WebhookRecord) *WebhookMessage {
+	msg := &WebhookMessage{Records: records, Dropped: h.dropped}
+
+	var sb strings.Builder
+	level := slog.TraceLevel
+	for i, wr := range records {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(wr.Text)
+		if wr.Count > 1 {
+			sb.WriteString(" (x" + strconv.Itoa(wr.Count) + ")")
+		}
+
+		msg.Total += wr.Count
+		if wr.Level < level {
+			level = wr.Level
+		}
+	}
+
+	if msg.Dropped > 0 {
+		sb.WriteString("\n... " + strconv.Itoa(msg.Dropped) + " records dropped")
+	}
+
+	msg.Text = sb.String()
+	msg.Level = level.Name()
+	msg.Title = "[" + msg.Level + "] " + strconv.Itoa(msg.Total) + " log records"
+	return msg
+}
+
+// send the pending records in messages. force will ignore the rate limit.
+func (h *WebhookHandler) flush(force bool) error {
+	var lastErr error
+	for len(h.records) > 0 {
+		if !force && !h.allow() {
+			return lastErr
+		}
+
+		n := len(h.records)
+		if n > h.MaxBatch {
+			n = h.MaxBatch
+		}
+
+		records := h.records[:n]
+		msg := h.message(records)
+
+		h.records = h.records[n:]
+		h.dropped = 0
+		for _, wr := range records {
+			delete(h.index, webhookRecordKey(wr.Level, wr.Channel, wr.Message))
+		}
+
+		if err := h.send(msg); err != nil {
+			h.AddDropped(msg.Total)
+			lastErr = err
+		}
+	}
+
+	h.records = nil
+	return lastErr
+}
+
+// allow check the rate limit, and record the send time.
+func (h *WebhookHandler) allow() bool {
+	if h.RateLimit <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	i := 0
+	for i < len(h.sent) && now.Sub(h.sent[i]) >= h.RatePeriod {
+		i++
+	}
+	h.sent = h.sent[i:]
+
+	if len(h.sent) >= h.RateLimit {
+		return false
+	}
+
+	h.sent = append(h.sent, now)
+	return true
+}
+
+func (h *WebhookHandler) send(msg *WebhookMessage) error {
+	var buf bytes.Buffer
+	if err := h.Template.Execute(&buf, msg); err != nil {
+		return err
+	}
+
+	body := buf.Bytes()
+	_, err := doHTTPWithRetry(h.Client, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", h.ContentType)
+		for k, v := range h.Headers {
+			req.Header.Set(k, v)
+		}
+		return req, nil
+	}, h.MaxRetries, h.Backoff)
+
+	if err == nil {
+		h.AddWritten(len(body))
+	}
+	return err
+}
+
+// Flush send the pending records, ignore the rate limit.
+func (h *WebhookHandler) Flush() error {
+	h.Lock()
+	defer h.Unlock()
+	return h.flush(true)
+}
+
+// Close stop the flush daemon, and send the pending records
+func (h *WebhookHandler) Close() error {
+	h.Lock()
+	if h.closed {
+		h.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.Unlock()
+
+	h.flusher.Stop()
+	return h.Flush()
+}
+
+func webhookRecordKey(level slog.Level, channel, message string) string {
+	return strconv.Itoa(int(level)) + "\x00" + channel + "\x00" + message
+}
@@ -0,0 +1,165 @@
+package handler_test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	srv := newRecordServer()
+	defer srv.Close()
+
+	h := handler.NewWebhookHandler(srv.URL, slog.DangerLevels, func(h *handler.WebhookHandler) {
+		h.Window = time.Hour
+		h.Headers = map[string]string{"X-Token": "abc"}
+	})
+
+	l := slog.NewWithHandlers(h)
+	l.ReportCaller = false
+	l.Info("info message")
+	for i := 0; i < 3; i++ {
+		l.Error("db is down")
+	}
+	l.Warn("disk is full")
+	assert.Len(t, srv.Bodies(), 0)
+
+	// records are aggregated into an message
+	assert.NoError(t, h.Flush())
+	bodies := srv.Bodies()
+	assert.Len(t, bodies, 1)
+	assert.Equal(t, "application/json", srv.requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "abc", srv.requests[0].Header.Get("X-Token"))
+
+	var payload map[string]string
+	assert.NoError(t, json.Unmarshal(bodies[0], &payload))
+	assert.Equal(t, "[ERROR] [application] db is down (x3)\n[WARNING] [application] disk is full", payload["text"])
+
+	assert.NoError(t, h.Close())
+	assert.Equal(t, handler.ErrHandlerClosed, h.Handle(&slog.Record{Message: "message"}))
+}
+
+func TestWebhookHandler_presets(t *testing.T) {
+	srv := newRecordServer()
+	defer srv.Close()
+
+	h := handler.NewWebhookHandler(srv.URL, slog.AllLevels, func(h *handler.WebhookHandler) {
+		h.Window = time.Hour
+		h.Template = template.Must(handler.NewWebhookTemplate(handler.WebhookPresetTeams))
+	})
+	defer h.Close()
+
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.WarnLevel, Message: `disk "/" is full`}))
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.ErrorLevel, Message: "db is down"}))
+	assert.NoError(t, h.Flush())
+
+	var payload map[string]string
+	assert.NoError(t, json.Unmarshal(srv.Bodies()[0], &payload))
+	assert.Equal(t, "MessageCard", payload["@type"])
+	assert.Equal(t, "[ERROR] 2 log records", payload["title"])
+	assert.Contains(t, payload["text"], `disk "/" is full`)
+
+	// custom template
+	h.Template = template.Must(handler.NewWebhookTemplate(`{"level": {{ json .Level }}, "count": {{ .Total }}, "first": {{ json (index .Records 0).Message }}}`))
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message"}))
+	assert.NoError(t, h.Flush())
+	assert.JSONEq(t, `{"level": "INFO", "count": 1, "first": "message"}`, string(srv.Bodies()[1]))
+
+	// the Discord content is truncated
+	h.Template = template.Must(handler.NewWebhookTemplate(handler.WebhookPresetDiscord))
+	h.SetFormatter(slog.NewTextFormatter("{{message}}"))
+	long := make([]byte, 3000)
+	for i := range long {
+		long[i] = 'a'
+	}
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: string(long)}))
+	assert.NoError(t, h.Flush())
+	assert.NoError(t, json.Unmarshal(srv.Bodies()[2], &payload))
+	assert.Len(t, payload["content"], 2000)
+}
+
+func TestNewWebhookTemplate_truncate(t *testing.T) {
+	tests := []struct {
+		tpl  string
+		text string
+		want string
+	}{
+		{`{{ truncate 10 .Text }}`, "short", "short"},
+		{`{{ truncate 8 .Text }}`, "0123456789", "01234..."},
+		{`{{ truncate 3 .Text }}`, "0123456789", "012"},
+		{`{{ truncate 1 .Text }}`, "0123456789", "0"},
+		{`{{ truncate 0 .Text }}`, "0123456789", ""},
+		{`{{ truncate -1 .Text }}`, "0123456789", ""},
+		// not break the multi-byte chars
+		{`{{ truncate 8 .Text }}`, "日本語テキスト", "日..."},
+		{`{{ truncate 9 .Text }}`, "日本語テキスト", "日本..."},
+		{`{{ truncate 2 .Text }}`, "日本語", ""},
+		{`{{ truncate 3 .Text }}`, "日本語", "日"},
+	}
+
+	for _, tt := range tests {
+		tpl := template.Must(handler.NewWebhookTemplate(tt.tpl))
+		buf := new(bytes.Buffer)
+		assert.NoError(t, tpl.Execute(buf, &handler.WebhookMessage{Text: tt.text}))
+		assert.Equal(t, tt.want, buf.String(), tt.tpl)
+		assert.True(t, utf8.ValidString(buf.String()))
+	}
+}
+
+func TestWebhookHandler_rateLimit(t *testing.T) {
+	srv := newRecordServer()
+	defer srv.Close()
+
+	h := handler.NewWebhookHandler(srv.URL, slog.AllLevels, func(h *handler.WebhookHandler) {
+		h.Window = 10 * time.Millisecond
+		h.MaxBatch = 1
+		h.MaxPending = 2
+		h.RateLimit = 1
+		h.RatePeriod = time.Hour
+	})
+	defer h.Close()
+
+	for _, msg := range []string{"message 1", "message 2", "message 3", "message 4"} {
+		assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: msg}))
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// only one message is sent, the pending records are kept, and the others are dropped
+	assert.Len(t, srv.Bodies(), 1)
+	assert.Equal(t, uint64(1), h.Dropped())
+
+	// flush will ignore the rate limit
+	assert.NoError(t, h.Flush())
+	bodies := srv.Bodies()
+	assert.Len(t, bodies, 3)
+	assert.Contains(t, string(bodies[1]), `message 2\n... 1 records dropped`)
+}
+
+func TestWebhookHandler_tls(t *testing.T) {
+	var body []byte
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	h := handler.NewWebhookHandler(srv.URL, slog.AllLevels, func(h *handler.WebhookHandler) {
+		h.Window = time.Hour
+		h.TLSConfig = &tls.Config{InsecureSkipVerify: true}
+	})
+	defer h.Close()
+
+	assert.NoError(t, h.Handle(&slog.Record{Level: slog.InfoLevel, Message: "message"}))
+	assert.NoError(t, h.Flush())
+	assert.Contains(t, string(body), "message")
+}
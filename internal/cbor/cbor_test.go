@@ -0,0 +1,144 @@
+package cbor_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog/internal/cbor"
+)
+
+func TestAppendValue(t *testing.T) {
+	// the examples from RFC 8949 Appendix A
+	tests := []struct {
+		in  interface{}
+		hex string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{100, "1864"},
+		{1000, "1903e8"},
+		{1000000, "1a000f4240"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{1.1, "fb3ff199999999999a"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{"", "60"},
+		{"IETF", "6449455446"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]int{1, 2, 3}, "83010203"},
+		{map[string]interface{}{"a": 1, "b": []int{2, 3}}, "a26161016162820203"},
+		{time.Unix(1363896240, 0), "c11a514b67b0"},
+		{time.Unix(1363896240, 500000000), "c1fb41d452d9ec200000"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.hex, hex.EncodeToString(cbor.AppendValue(nil, tt.in)), "%v", tt.in)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{12, int64(12)},
+		{-500, int64(-500)},
+		{int64(math.MinInt64), int64(math.MinInt64)},
+		{uint64(math.MaxUint64), uint64(math.MaxUint64)},
+		{float32(1.5), 1.5},
+		{"abc", "abc"},
+		{strings.Repeat("a", 300), strings.Repeat("a", 300)},
+		{[]byte("bin"), []byte("bin")},
+		{errors.New("an error"), "an error"},
+		{time.Unix(1614600000, 123456000), time.Unix(1614600000, 123456000)},
+		{map[string]string{"k": "v"}, map[string]interface{}{"k": "v"}},
+		{map[string]interface{}{"n": map[string]interface{}{"t": []interface{}{1, "a"}}},
+			map[string]interface{}{"n": map[string]interface{}{"t": []interface{}{int64(1), "a"}}}},
+	}
+
+	for _, tt := range tests {
+		v, err := cbor.Unmarshal(cbor.AppendValue(nil, tt.in))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, v, "%v", tt.in)
+	}
+
+	// half float and other tags
+	bs, _ := hex.DecodeString("f93e00")
+	v, err := cbor.Unmarshal(bs)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, v)
+
+	v, err = cbor.Unmarshal(cbor.AppendString(cbor.AppendTag(nil, 32), "http://a"))
+	assert.NoError(t, err)
+	assert.Equal(t, cbor.Tag{Number: 32, Content: "http://a"}, v)
+}
+
+func TestDecoder_invalid(t *testing.T) {
+	// indefinite length array
+	_, err := cbor.Unmarshal([]byte{0x9f, 0x01, 0xff})
+	assert.Equal(t, cbor.ErrInvalidFormat, err)
+
+	_, err = cbor.Unmarshal([]byte{0x63, 'a'})
+	assert.Error(t, err)
+}
+
+// the allocated bytes on call fn
+func allocBytes(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func TestDecoder_corrupt(t *testing.T) {
+	// the huge length in the header will not alloc the memory before read the data
+	for _, data := range [][]byte{
+		{0x5a, 0x7f, 0xff, 0xff, 0xff, 'a'},
+		{0x7a, 0x7f, 0xff, 0xff, 0xff, 'a'},
+		{0x9a, 0x7f, 0xff, 0xff, 0xff, 0x01},
+		{0xba, 0x7f, 0xff, 0xff, 0xff, 0x61, 'a', 0x01},
+		{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	} {
+		n := allocBytes(func() {
+			_, err := cbor.Unmarshal(data)
+			assert.Error(t, err)
+		})
+		assert.True(t, n < 1<<20, "alloc %d bytes for % x", n, data)
+	}
+
+	// the large data is read in chunks
+	data := append([]byte{0x5a, 0x00, 0x02, 0x00, 0x00}, make([]byte, 128<<10)...)
+	v, err := cbor.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Len(t, v, 128<<10)
+
+	// the random corrupt input will not panic
+	valid := cbor.AppendValue(nil, map[string]interface{}{
+		"time":  time.Unix(1614600000, 0),
+		"list":  []interface{}{1, "two", 3.5, nil, true},
+		"bytes": []byte("abc"),
+		"map":   map[string]interface{}{"key": "value"},
+	})
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		data := append([]byte(nil), valid...)
+		for j := 0; j < 3; j++ {
+			data[rnd.Intn(len(data))] = byte(rnd.Intn(256))
+		}
+		_, _ = cbor.Unmarshal(data[:rnd.Intn(len(data)+1)])
+	}
+}
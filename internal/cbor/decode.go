@@ -0,0 +1,249 @@
+package cbor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ErrInvalidFormat returns on decode an invalid or unsupported initial byte. eg: the indefinite length items
+var ErrInvalidFormat = errors.New("cbor: invalid format")
+
+// the max size of the memory preallocated by the length in the input.
+// the larger data is read in chunks, so an corrupt length will not alloc huge memory before read the data.
+const (
+	maxPreallocBytes = 64 << 10
+	maxPreallocItems = 1024
+)
+
+// Tag an tagged value, except the epoch-based date/time
+type Tag struct {
+	Number  uint64
+	Content interface{}
+}
+
+// Decoder read and decode values from an reader.
+//
+// The decoded values:
+//
+// - nil, bool, int64, uint64(only for the value > math.MaxInt64), float64, string, []byte
+// - []interface{} for array, map[string]interface{} for map
+// - time.Time for the epoch-based date/time(tag 1), Tag for the other tags
+//
+// NOTICE: the indefinite length items are not supported.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder create new Decoder
+func NewDecoder(r io.Reader) *Decoder {
+	if br, ok := r.(*bufio.Reader); ok {
+		return &Decoder{r: br}
+	}
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Unmarshal decode an value from the data
+func Unmarshal(data []byte) (interface{}, error) {
+	return NewDecoder(bytes.NewReader(data)).Decode()
+}
+
+// Decode read and decode an value
+func (d *Decoder) Decode() (interface{}, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	major, info := c>>5, c&0x1f
+	if major == majorSimple {
+		return d.decodeSimple(info)
+	}
+
+	n, err := d.readArg(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case majorNegInt:
+		if n > math.MaxInt64 {
+			return nil, ErrInvalidFormat
+		}
+		return -1 - int64(n), nil
+	case majorBytes:
+		return d.readN(n)
+	case majorText:
+		buf, err := d.readN(n)
+		return string(buf), err
+	case majorArray:
+		if n > math.MaxInt32 {
+			return nil, ErrInvalidFormat
+		}
+
+		arr := make([]interface{}, 0, preallocItems(n))
+		for i := uint64(0); i < n; i++ {
+			v, err := d.Decode()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case majorMap:
+		if n > math.MaxInt32 {
+			return nil, ErrInvalidFormat
+		}
+		return d.decodeMap(n)
+	}
+
+	// majorTag
+	content, err := d.Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	if n == TagEpochTime {
+		switch tv := content.(type) {
+		case int64:
+			return time.Unix(tv, 0), nil
+		case float64:
+			sec, frac := math.Modf(tv)
+			// the float64 has the microseconds precision for the current time
+			return time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3), nil
+		}
+		return nil, ErrInvalidFormat
+	}
+	return Tag{Number: n, Content: content}, nil
+}
+
+// read the argument by the additional info
+func (d *Decoder) readArg(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	if info > 27 {
+		return 0, ErrInvalidFormat
+	}
+
+	buf, err := d.readN(1 << (info - 24))
+	if err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	for _, c := range buf {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (d *Decoder) decodeSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null, undefined
+		return nil, nil
+	case 25:
+		buf, err := d.readN(2)
+		if err != nil {
+			return nil, err
+		}
+		return halfToFloat64(uint16(buf[0])<<8 | uint16(buf[1])), nil
+	case 26:
+		v, err := d.readArg(26)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 27:
+		v, err := d.readArg(27)
+		return math.Float64frombits(v), err
+	}
+	return nil, ErrInvalidFormat
+}
+
+func (d *Decoder) readN(n uint64) ([]byte, error) {
+	// the length should be checked before alloc
+	if n > math.MaxInt32 {
+		return nil, ErrInvalidFormat
+	}
+
+	if n <= maxPreallocBytes {
+		buf := make([]byte, n)
+		_, err := io.ReadFull(d.r, buf)
+		return buf, err
+	}
+
+	// the buffer is grown by the read data
+	buf := bytes.NewBuffer(make([]byte, 0, maxPreallocBytes))
+	if _, err := io.CopyN(buf, d.r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *Decoder) decodeMap(n uint64) (interface{}, error) {
+	mp := make(map[string]interface{}, preallocItems(n))
+	for i := uint64(0); i < n; i++ {
+		k, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+
+		if ks, ok := k.(string); ok {
+			mp[ks] = v
+		} else {
+			mp[fmt.Sprint(k)] = v
+		}
+	}
+	return mp, nil
+}
+
+func preallocItems(n uint64) int {
+	if n > maxPreallocItems {
+		return maxPreallocItems
+	}
+	return int(n)
+}
+
+// convert the IEEE 754 half-precision float
+func halfToFloat64(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+
+	if h&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,232 @@
+// Package cbor is an minimal CBOR(RFC 8949) encoder and decoder for the log formatters.
+//
+// spec: https://www.rfc-editor.org/rfc/rfc8949.html
+package cbor
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// the major types
+const (
+	majorUint   byte = 0
+	majorNegInt byte = 1
+	majorBytes  byte = 2
+	majorText   byte = 3
+	majorArray  byte = 4
+	majorMap    byte = 5
+	majorTag    byte = 6
+	majorSimple byte = 7
+)
+
+// TagEpochTime the tag number of the epoch-based date/time
+const TagEpochTime uint64 = 1
+
+// append the initial byte and the argument, use the smallest format.
+func appendHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return append(b, major|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return append(b, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, major|27, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+// AppendNil append an null value
+func AppendNil(b []byte) []byte {
+	return append(b, 0xf6)
+}
+
+// AppendBool append an bool value
+func AppendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xf5)
+	}
+	return append(b, 0xf4)
+}
+
+// AppendInt append an int value
+func AppendInt(b []byte, v int64) []byte {
+	if v >= 0 {
+		return appendHead(b, majorUint, uint64(v))
+	}
+	return appendHead(b, majorNegInt, uint64(-1-v))
+}
+
+// AppendUint append an uint value
+func AppendUint(b []byte, v uint64) []byte {
+	return appendHead(b, majorUint, v)
+}
+
+// AppendFloat64 append an float64 value
+func AppendFloat64(b []byte, v float64) []byte {
+	n := math.Float64bits(v)
+	return append(b, majorSimple<<5|27, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+// AppendString append an text string value
+func AppendString(b []byte, s string) []byte {
+	return append(appendHead(b, majorText, uint64(len(s))), s...)
+}
+
+// AppendBytes append an byte string value
+func AppendBytes(b []byte, v []byte) []byte {
+	return append(appendHead(b, majorBytes, uint64(len(v))), v...)
+}
+
+// AppendArrayHeader append the header of an array with n elements
+func AppendArrayHeader(b []byte, n int) []byte {
+	return appendHead(b, majorArray, uint64(n))
+}
+
+// AppendMapHeader append the header of an map with n pairs
+func AppendMapHeader(b []byte, n int) []byte {
+	return appendHead(b, majorMap, uint64(n))
+}
+
+// AppendTag append the head of an tagged value, the tag content should be appended after it.
+func AppendTag(b []byte, tag uint64) []byte {
+	return appendHead(b, majorTag, tag)
+}
+
+// AppendTime append the time as the epoch-based date/time(tag 1).
+// the content is an integer if the time has no fractional seconds, otherwise an float64.
+func AppendTime(b []byte, t time.Time) []byte {
+	b = AppendTag(b, TagEpochTime)
+	if t.Nanosecond() == 0 {
+		return AppendInt(b, t.Unix())
+	}
+	return AppendFloat64(b, float64(t.UnixNano())/1e9)
+}
+
+// AppendValue append an value. supported:
+//
+// - nil, bool, all int, uint and float types, string, []byte
+// - time.Time will be encoded as the epoch-based date/time(tag 1)
+// - error and fmt.Stringer will be encoded as an string
+// - map with string keys, slice and array. the map keys will be sorted
+// - others will be encoded as an string by fmt.Sprint()
+func AppendValue(b []byte, v interface{}) []byte {
+	switch tv := v.(type) {
+	case nil:
+		return AppendNil(b)
+	case bool:
+		return AppendBool(b, tv)
+	case int:
+		return AppendInt(b, int64(tv))
+	case int8:
+		return AppendInt(b, int64(tv))
+	case int16:
+		return AppendInt(b, int64(tv))
+	case int32:
+		return AppendInt(b, int64(tv))
+	case int64:
+		return AppendInt(b, tv)
+	case uint:
+		return AppendUint(b, uint64(tv))
+	case uint8:
+		return AppendUint(b, uint64(tv))
+	case uint16:
+		return AppendUint(b, uint64(tv))
+	case uint32:
+		return AppendUint(b, uint64(tv))
+	case uint64:
+		return AppendUint(b, tv)
+	case float32:
+		return AppendFloat64(b, float64(tv))
+	case float64:
+		return AppendFloat64(b, tv)
+	case string:
+		return AppendString(b, tv)
+	case []byte:
+		return AppendBytes(b, tv)
+	case time.Time:
+		return AppendTime(b, tv)
+	case error:
+		return AppendString(b, tv.Error())
+	case map[string]interface{}:
+		return appendStringMap(b, tv)
+	case fmt.Stringer:
+		// the map type. eg: slog.M
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Map {
+			return appendReflect(b, rv)
+		}
+		return AppendString(b, tv.String())
+	case []interface{}:
+		b = AppendArrayHeader(b, len(tv))
+		for _, item := range tv {
+			b = AppendValue(b, item)
+		}
+		return b
+	}
+
+	return appendReflect(b, reflect.ValueOf(v))
+}
+
+func appendStringMap(b []byte, mp map[string]interface{}) []byte {
+	keys := make([]string, 0, len(mp))
+	for k := range mp {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b = AppendMapHeader(b, len(mp))
+	for _, k := range keys {
+		b = AppendString(b, k)
+		b = AppendValue(b, mp[k])
+	}
+	return b
+}
+
+func appendReflect(b []byte, rv reflect.Value) []byte {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return AppendNil(b)
+		}
+		return AppendValue(b, rv.Elem().Interface())
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		b = AppendMapHeader(b, len(keys))
+		for _, key := range keys {
+			b = AppendString(b, key.String())
+			b = AppendValue(b, rv.MapIndex(key).Interface())
+		}
+		return b
+	case reflect.Slice, reflect.Array:
+		n := rv.Len()
+		b = AppendArrayHeader(b, n)
+		for i := 0; i < n; i++ {
+			b = AppendValue(b, rv.Index(i).Interface())
+		}
+		return b
+	case reflect.String:
+		return AppendString(b, rv.String())
+	case reflect.Bool:
+		return AppendBool(b, rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return AppendInt(b, rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return AppendUint(b, rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return AppendFloat64(b, rv.Float())
+	}
+
+	return AppendString(b, fmt.Sprint(rv.Interface()))
+}
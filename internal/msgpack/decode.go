@@ -0,0 +1,277 @@
+package msgpack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ErrInvalidFormat returns on decode an invalid format byte
+var ErrInvalidFormat = errors.New("msgpack: invalid format")
+
+// the max size of the memory preallocated by the length in the input.
+// the larger data is read in chunks, so an corrupt length will not alloc huge memory before read the data.
+const (
+	maxPreallocBytes = 64 << 10
+	maxPreallocItems = 1024
+)
+
+// Ext an extension value
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+// EventTime decode the Fluentd EventTime. return false if it is not an EventTime
+func (e Ext) EventTime() (time.Time, bool) {
+	if e.Type != EventTimeExt || len(e.Data) != 8 {
+		return time.Time{}, false
+	}
+
+	sec := binary.BigEndian.Uint32(e.Data)
+	nsec := binary.BigEndian.Uint32(e.Data[4:])
+	return time.Unix(int64(sec), int64(nsec)), true
+}
+
+// Timestamp decode the msgpack timestamp. return false if it is not an timestamp
+func (e Ext) Timestamp() (time.Time, bool) {
+	if e.Type != TimestampExt {
+		return time.Time{}, false
+	}
+
+	switch len(e.Data) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(e.Data)), 0), true
+	case 8:
+		v := binary.BigEndian.Uint64(e.Data)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)), true
+	case 12:
+		nsec := binary.BigEndian.Uint32(e.Data)
+		sec := binary.BigEndian.Uint64(e.Data[4:])
+		return time.Unix(int64(sec), int64(nsec)), true
+	}
+	return time.Time{}, false
+}
+
+// Decoder read and decode values from an reader.
+//
+// The decoded values:
+//
+// - nil, bool, int64, uint64(only for the value > math.MaxInt64), float64, string, []byte
+// - []interface{} for array, map[string]interface{} for map, Ext for extension
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder create new Decoder
+func NewDecoder(r io.Reader) *Decoder {
+	if br, ok := r.(*bufio.Reader); ok {
+		return &Decoder{r: br}
+	}
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Unmarshal decode an value from the data
+func Unmarshal(data []byte) (interface{}, error) {
+	return NewDecoder(bytes.NewReader(data)).Decode()
+}
+
+// Decode read and decode an value
+func (d *Decoder) Decode() (interface{}, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c <= 0x7f: // positive fixint
+		return int64(c), nil
+	case c >= 0xe0: // negative fixint
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80: // fixmap
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90: // fixarray
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0: // fixstr
+		return d.readString(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6: // bin
+		n, err := d.readLen(c - 0xc4)
+		if err != nil {
+			return nil, err
+		}
+		return d.readN(n)
+	case 0xc7, 0xc8, 0xc9: // ext
+		n, err := d.readLen(c - 0xc7)
+		if err != nil {
+			return nil, err
+		}
+		return d.readExt(n)
+	case 0xca:
+		v, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.readUint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf: // uint
+		v, err := d.readUint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3: // int
+		size := 1 << (c - 0xd0)
+		v, err := d.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+
+		// sign extension
+		shift := uint(64 - size*8)
+		return int64(v<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8: // fixext
+		return d.readExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb: // str
+		n, err := d.readLen(c - 0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return d.readString(n)
+	case 0xdc, 0xdd: // array
+		n, err := d.readLen(c - 0xdc + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n)
+	case 0xde, 0xdf: // map
+		n, err := d.readLen(c - 0xde + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n)
+	}
+
+	return nil, ErrInvalidFormat
+}
+
+// read the length. sizeIdx: 0 is 1 byte, 1 is 2 bytes, 2 is 4 bytes.
+func (d *Decoder) readLen(sizeIdx byte) (int, error) {
+	v, err := d.readUint(1 << sizeIdx)
+	if err != nil {
+		return 0, err
+	}
+
+	if v > math.MaxInt32 {
+		return 0, ErrInvalidFormat
+	}
+	return int(v), nil
+}
+
+func (d *Decoder) readUint(size int) (uint64, error) {
+	buf, err := d.readN(size)
+	if err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	for _, c := range buf {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (d *Decoder) readN(n int) ([]byte, error) {
+	if n <= maxPreallocBytes {
+		buf := make([]byte, n)
+		_, err := io.ReadFull(d.r, buf)
+		return buf, err
+	}
+
+	// the buffer is grown by the read data
+	buf := bytes.NewBuffer(make([]byte, 0, maxPreallocBytes))
+	if _, err := io.CopyN(buf, d.r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *Decoder) readString(n int) (interface{}, error) {
+	buf, err := d.readN(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(buf), nil
+}
+
+func (d *Decoder) readExt(n int) (interface{}, error) {
+	typ, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := d.readN(n)
+	if err != nil {
+		return nil, err
+	}
+	return Ext{Type: int8(typ), Data: data}, nil
+}
+
+func (d *Decoder) decodeArray(n int) (interface{}, error) {
+	arr := make([]interface{}, 0, preallocItems(n))
+	for i := 0; i < n; i++ {
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+func (d *Decoder) decodeMap(n int) (interface{}, error) {
+	mp := make(map[string]interface{}, preallocItems(n))
+	for i := 0; i < n; i++ {
+		k, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+
+		if ks, ok := k.(string); ok {
+			mp[ks] = v
+		} else {
+			mp[fmt.Sprint(k)] = v
+		}
+	}
+	return mp, nil
+}
+
+func preallocItems(n int) int {
+	if n > maxPreallocItems {
+		return maxPreallocItems
+	}
+	return n
+}
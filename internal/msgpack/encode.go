@@ -0,0 +1,310 @@
+// Package msgpack is an minimal MessagePack encoder and decoder for the log handlers and formatters.
+//
+// spec: https://github.com/msgpack/msgpack/blob/master/spec.md
+package msgpack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// the extension types
+const (
+	// EventTimeExt the ext type of the Fluentd EventTime
+	EventTimeExt int8 = 0
+	// TimestampExt the ext type of the msgpack timestamp
+	TimestampExt int8 = -1
+)
+
+// AppendNil append an nil value
+func AppendNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+// AppendBool append an bool value
+func AppendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+// AppendInt append an int value, use the smallest format.
+func AppendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return AppendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return append(b, 0xd1, byte(v>>8), byte(v))
+	case v >= math.MinInt32:
+		return appendUint32(append(b, 0xd2), uint32(v))
+	}
+	return appendUint64(append(b, 0xd3), uint64(v))
+}
+
+// AppendUint append an uint value, use the smallest format.
+func AppendUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= math.MaxInt8:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return append(b, 0xcd, byte(v>>8), byte(v))
+	case v <= math.MaxUint32:
+		return appendUint32(append(b, 0xce), uint32(v))
+	}
+	return appendUint64(append(b, 0xcf), v)
+}
+
+// AppendFloat32 append an float32 value
+func AppendFloat32(b []byte, v float32) []byte {
+	return appendUint32(append(b, 0xca), math.Float32bits(v))
+}
+
+// AppendFloat64 append an float64 value
+func AppendFloat64(b []byte, v float64) []byte {
+	return appendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+// AppendString append an string value
+func AppendString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = appendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// AppendBytes append an binary value
+func AppendBytes(b []byte, v []byte) []byte {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xc5, byte(n>>8), byte(n))
+	default:
+		b = appendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, v...)
+}
+
+// AppendArrayHeader append the header of an array with n elements
+func AppendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xdc, byte(n>>8), byte(n))
+	}
+	return appendUint32(append(b, 0xdd), uint32(n))
+}
+
+// AppendMapHeader append the header of an map with n pairs
+func AppendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xde, byte(n>>8), byte(n))
+	}
+	return appendUint32(append(b, 0xdf), uint32(n))
+}
+
+// AppendExt append an extension value
+func AppendExt(b []byte, typ int8, data []byte) []byte {
+	n := len(data)
+	switch n {
+	case 1:
+		b = append(b, 0xd4, byte(typ))
+	case 2:
+		b = append(b, 0xd5, byte(typ))
+	case 4:
+		b = append(b, 0xd6, byte(typ))
+	case 8:
+		b = append(b, 0xd7, byte(typ))
+	case 16:
+		b = append(b, 0xd8, byte(typ))
+	default:
+		switch {
+		case n <= math.MaxUint8:
+			b = append(b, 0xc7, byte(n), byte(typ))
+		case n <= math.MaxUint16:
+			b = append(b, 0xc8, byte(n>>8), byte(n), byte(typ))
+		default:
+			b = append(appendUint32(append(b, 0xc9), uint32(n)), byte(typ))
+		}
+	}
+	return append(b, data...)
+}
+
+// AppendEventTime append the time as the Fluentd EventTime(ext type 0).
+// the data is the seconds and nanoseconds as uint32 big-endian.
+func AppendEventTime(b []byte, t time.Time) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(data[4:], uint32(t.Nanosecond()))
+	return AppendExt(b, EventTimeExt, data)
+}
+
+// AppendTimestamp append the time as the msgpack timestamp(ext type -1).
+// use the timestamp 64 format if the seconds fits in 34 bits, otherwise the timestamp 96 format.
+func AppendTimestamp(b []byte, t time.Time) []byte {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	if sec >= 0 && sec < 1<<34 {
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, nsec<<34|uint64(sec))
+		return AppendExt(b, TimestampExt, data)
+	}
+
+	data := make([]byte, 12)
+	binary.BigEndian.PutUint32(data, uint32(nsec))
+	binary.BigEndian.PutUint64(data[4:], uint64(sec))
+	return AppendExt(b, TimestampExt, data)
+}
+
+// AppendValue append an value. supported:
+//
+// - nil, bool, all int, uint and float types, string, []byte
+// - time.Time will be encoded as an RFC3339 string
+// - error and fmt.Stringer will be encoded as an string
+// - map with string keys, slice and array. the map keys will be sorted
+// - others will be encoded as an string by fmt.Sprint()
+func AppendValue(b []byte, v interface{}) []byte {
+	switch tv := v.(type) {
+	case nil:
+		return AppendNil(b)
+	case bool:
+		return AppendBool(b, tv)
+	case int:
+		return AppendInt(b, int64(tv))
+	case int8:
+		return AppendInt(b, int64(tv))
+	case int16:
+		return AppendInt(b, int64(tv))
+	case int32:
+		return AppendInt(b, int64(tv))
+	case int64:
+		return AppendInt(b, tv)
+	case uint:
+		return AppendUint(b, uint64(tv))
+	case uint8:
+		return AppendUint(b, uint64(tv))
+	case uint16:
+		return AppendUint(b, uint64(tv))
+	case uint32:
+		return AppendUint(b, uint64(tv))
+	case uint64:
+		return AppendUint(b, tv)
+	case float32:
+		return AppendFloat32(b, tv)
+	case float64:
+		return AppendFloat64(b, tv)
+	case string:
+		return AppendString(b, tv)
+	case []byte:
+		return AppendBytes(b, tv)
+	case time.Time:
+		return AppendString(b, tv.Format(time.RFC3339Nano))
+	case error:
+		return AppendString(b, tv.Error())
+	case map[string]interface{}:
+		return appendStringMap(b, tv)
+	case fmt.Stringer:
+		// the map type. eg: slog.M
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Map {
+			return appendReflect(b, rv)
+		}
+		return AppendString(b, tv.String())
+	case []interface{}:
+		b = AppendArrayHeader(b, len(tv))
+		for _, item := range tv {
+			b = AppendValue(b, item)
+		}
+		return b
+	}
+
+	return appendReflect(b, reflect.ValueOf(v))
+}
+
+func appendStringMap(b []byte, mp map[string]interface{}) []byte {
+	keys := make([]string, 0, len(mp))
+	for k := range mp {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b = AppendMapHeader(b, len(mp))
+	for _, k := range keys {
+		b = AppendString(b, k)
+		b = AppendValue(b, mp[k])
+	}
+	return b
+}
+
+func appendReflect(b []byte, rv reflect.Value) []byte {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return AppendNil(b)
+		}
+		return AppendValue(b, rv.Elem().Interface())
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		b = AppendMapHeader(b, len(keys))
+		for _, key := range keys {
+			b = AppendString(b, key.String())
+			b = AppendValue(b, rv.MapIndex(key).Interface())
+		}
+		return b
+	case reflect.Slice, reflect.Array:
+		n := rv.Len()
+		b = AppendArrayHeader(b, n)
+		for i := 0; i < n; i++ {
+			b = AppendValue(b, rv.Index(i).Interface())
+		}
+		return b
+	case reflect.String:
+		return AppendString(b, rv.String())
+	case reflect.Bool:
+		return AppendBool(b, rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return AppendInt(b, rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return AppendUint(b, rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return AppendFloat64(b, rv.Float())
+	}
+
+	return AppendString(b, fmt.Sprint(rv.Interface()))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return append(b, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
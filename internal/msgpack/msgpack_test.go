@@ -0,0 +1,165 @@
+package msgpack_test
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog/internal/msgpack"
+)
+
+func TestAppendValue(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{false, false},
+		{12, int64(12)},
+		{-12, int64(-12)},
+		{-100, int64(-100)},
+		{200, int64(200)},
+		{-200, int64(-200)},
+		{70000, int64(70000)},
+		{-70000, int64(-70000)},
+		{int64(math.MinInt64), int64(math.MinInt64)},
+		{uint64(math.MaxUint64), uint64(math.MaxUint64)},
+		{float32(1.5), float64(1.5)},
+		{2.25, 2.25},
+		{"abc", "abc"},
+		{strings.Repeat("a", 40), strings.Repeat("a", 40)},
+		{strings.Repeat("a", 300), strings.Repeat("a", 300)},
+		{[]byte("bin"), []byte("bin")},
+		{errors.New("an error"), "an error"},
+		{time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC), "2021-03-01T12:00:00Z"},
+		{[]int{1, 2}, []interface{}{int64(1), int64(2)}},
+		{map[string]string{"k": "v"}, map[string]interface{}{"k": "v"}},
+		{
+			map[string]interface{}{"k1": 1, "k2": []interface{}{"a", nil}},
+			map[string]interface{}{"k1": int64(1), "k2": []interface{}{"a", nil}},
+		},
+	}
+
+	for _, tt := range tests {
+		v, err := msgpack.Unmarshal(msgpack.AppendValue(nil, tt.in))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, v)
+	}
+
+	// large array and map
+	arr := make([]interface{}, 20)
+	mp := make(map[string]interface{}, 20)
+	for i := range arr {
+		arr[i] = int64(i)
+		mp[string(rune('a'+i))] = int64(i)
+	}
+
+	v, err := msgpack.Unmarshal(msgpack.AppendValue(nil, arr))
+	assert.NoError(t, err)
+	assert.Equal(t, arr, v)
+	v, err = msgpack.Unmarshal(msgpack.AppendValue(nil, mp))
+	assert.NoError(t, err)
+	assert.Equal(t, mp, v)
+}
+
+func TestAppendEventTime(t *testing.T) {
+	tm := time.Unix(1614600000, 123456789)
+	bts := msgpack.AppendEventTime(nil, tm)
+	assert.Equal(t, byte(0xd7), bts[0])
+
+	v, err := msgpack.Unmarshal(bts)
+	assert.NoError(t, err)
+
+	ext, ok := v.(msgpack.Ext)
+	assert.True(t, ok)
+	et, ok := ext.EventTime()
+	assert.True(t, ok)
+	assert.True(t, tm.Equal(et))
+
+	// ext with other size
+	v, err = msgpack.Unmarshal(msgpack.AppendExt(nil, 5, []byte("abc")))
+	assert.NoError(t, err)
+	assert.Equal(t, msgpack.Ext{Type: 5, Data: []byte("abc")}, v)
+}
+
+func TestAppendTimestamp(t *testing.T) {
+	for _, tm := range []time.Time{
+		time.Unix(1614600000, 123456789),
+		// out of the timestamp 64 range
+		time.Unix(-1, 500),
+		time.Unix(1<<35, 0),
+	} {
+		v, err := msgpack.Unmarshal(msgpack.AppendTimestamp(nil, tm))
+		assert.NoError(t, err)
+
+		ts, ok := v.(msgpack.Ext).Timestamp()
+		assert.True(t, ok)
+		assert.True(t, tm.Equal(ts))
+	}
+
+	_, ok := msgpack.Ext{Type: msgpack.TimestampExt, Data: []byte{1}}.Timestamp()
+	assert.False(t, ok)
+}
+
+func TestDecoder_invalid(t *testing.T) {
+	_, err := msgpack.Unmarshal([]byte{0xc1})
+	assert.Equal(t, msgpack.ErrInvalidFormat, err)
+
+	_, err = msgpack.Unmarshal([]byte{0xa3, 'a'})
+	assert.Error(t, err)
+}
+
+// the allocated bytes on call fn
+func allocBytes(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func TestDecoder_corrupt(t *testing.T) {
+	// the huge length in the header will not alloc the memory before read the data
+	for _, data := range [][]byte{
+		{0xc6, 0xff, 0xff, 0xff, 0xff},
+		{0xc6, 0x7f, 0xff, 0xff, 0xff, 'a'},
+		{0xdb, 0x7f, 0xff, 0xff, 0xff, 'a'},
+		{0xc9, 0x7f, 0xff, 0xff, 0xff, 0x01},
+		{0xdd, 0x7f, 0xff, 0xff, 0xff, 0x01},
+		{0xdf, 0x7f, 0xff, 0xff, 0xff, 0xa1, 'a', 0x01},
+	} {
+		n := allocBytes(func() {
+			_, err := msgpack.Unmarshal(data)
+			assert.Error(t, err)
+		})
+		assert.True(t, n < 1<<20, "alloc %d bytes for % x", n, data)
+	}
+
+	// the large data is read in chunks
+	data := append([]byte{0xc6, 0x00, 0x02, 0x00, 0x00}, make([]byte, 128<<10)...)
+	v, err := msgpack.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Len(t, v, 128<<10)
+
+	// the random corrupt input will not panic
+	valid := msgpack.AppendValue(nil, map[string]interface{}{
+		"time":  time.Unix(1614600000, 0),
+		"list":  []interface{}{1, "two", 3.5, nil, true},
+		"bytes": []byte("abc"),
+		"map":   map[string]interface{}{"key": "value"},
+	})
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		data := append([]byte(nil), valid...)
+		for j := 0; j < 3; j++ {
+			data[rnd.Intn(len(data))] = byte(rnd.Intn(256))
+		}
+		_, _ = msgpack.Unmarshal(data[:rnd.Intn(len(data)+1)])
+	}
+}
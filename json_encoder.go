@@ -0,0 +1,305 @@
+package slog
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// the max buffer size for put back to the pool
+const maxPooledJSONBuffer = 64 << 10
+
+var jsonBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 512)
+		return &buf
+	},
+}
+
+const hexDigits = "0123456789abcdef"
+
+// jsonEncoder an append-style JSON encoder, the output is same as the encoding/json, except:
+//
+// - the error and fmt.Stringer values are encoded as string. encoding/json encode them by the exported fields
+// - the NaN and Inf floats are encoded as string. encoding/json returns an error
+// - the HTML chars <, >, & are escaped only on escapeHTML is true
+//
+// The common types are encoded directly, others are fallback to the encoding/json.
+type jsonEncoder struct {
+	escapeHTML bool
+}
+
+func (e jsonEncoder) appendValue(buf []byte, v interface{}) []byte {
+	switch tv := v.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return e.appendString(buf, tv)
+	case bool:
+		return strconv.AppendBool(buf, tv)
+	case int:
+		return strconv.AppendInt(buf, int64(tv), 10)
+	case int8:
+		return strconv.AppendInt(buf, int64(tv), 10)
+	case int16:
+		return strconv.AppendInt(buf, int64(tv), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(tv), 10)
+	case int64:
+		return strconv.AppendInt(buf, tv, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(tv), 10)
+	case uint8:
+		return strconv.AppendUint(buf, uint64(tv), 10)
+	case uint16:
+		return strconv.AppendUint(buf, uint64(tv), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(tv), 10)
+	case uint64:
+		return strconv.AppendUint(buf, tv, 10)
+	case float32:
+		return e.appendFloat(buf, float64(tv), 32)
+	case float64:
+		return e.appendFloat(buf, tv, 64)
+	case M:
+		return e.appendMap(buf, tv)
+	case map[string]interface{}:
+		return e.appendMap(buf, tv)
+	case map[string]string:
+		if tv == nil {
+			return append(buf, "null"...)
+		}
+
+		keys := make([]string, 0, len(tv))
+		for k := range tv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf = append(buf, '{')
+		for i, k := range keys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(e.appendString(buf, k), ':')
+			buf = e.appendString(buf, tv[k])
+		}
+		return append(buf, '}')
+	case []interface{}:
+		if tv == nil {
+			return append(buf, "null"...)
+		}
+
+		buf = append(buf, '[')
+		for i, item := range tv {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = e.appendValue(buf, item)
+		}
+		return append(buf, ']')
+	case []string:
+		if tv == nil {
+			return append(buf, "null"...)
+		}
+
+		buf = append(buf, '[')
+		for i, item := range tv {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = e.appendString(buf, item)
+		}
+		return append(buf, ']')
+	case []byte:
+		if tv == nil {
+			return append(buf, "null"...)
+		}
+
+		buf = append(buf, '"')
+		n := len(buf)
+		buf = append(buf, make([]byte, base64.StdEncoding.EncodedLen(len(tv)))...)
+		base64.StdEncoding.Encode(buf[n:], tv)
+		return append(buf, '"')
+	case time.Time:
+		buf = append(buf, '"')
+		buf = tv.AppendFormat(buf, time.RFC3339Nano)
+		return append(buf, '"')
+	case json.Marshaler:
+		if isNilPointer(v) {
+			return append(buf, "null"...)
+		}
+		return e.appendMarshaler(buf, tv)
+	case error:
+		if isNilPointer(v) {
+			return append(buf, "null"...)
+		}
+		return e.appendString(buf, tv.Error())
+	case fmt.Stringer:
+		if isNilPointer(v) {
+			return append(buf, "null"...)
+		}
+		return e.appendString(buf, tv.String())
+	}
+
+	return e.appendReflect(buf, v)
+}
+
+// the keys are sorted, same as the encoding/json
+func (e jsonEncoder) appendMap(buf []byte, mp map[string]interface{}) []byte {
+	if mp == nil {
+		return append(buf, "null"...)
+	}
+
+	buf = append(buf, '{')
+	for i, k := range sortedMapKeys(mp) {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+
+		buf = append(e.appendString(buf, k), ':')
+		buf = e.appendValue(buf, mp[k])
+	}
+	return append(buf, '}')
+}
+
+// format the float same as the encoding/json
+func (e jsonEncoder) appendFloat(buf []byte, f float64, bits int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return e.appendString(buf, strconv.FormatFloat(f, 'g', -1, bits))
+	}
+
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	buf = strconv.AppendFloat(buf, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(buf)
+		if n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+	return buf
+}
+
+// escape the string same as the encoding/json
+func (e jsonEncoder) appendString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= ' ' && c != '"' && c != '\\' && (!e.escapeHTML || c != '<' && c != '>' && c != '&') {
+				i++
+				continue
+			}
+
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			case '\b':
+				buf = append(buf, '\\', 'b')
+			case '\f':
+				buf = append(buf, '\\', 'f')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+
+		// the U+2028 and U+2029 are escaped for the JSONP
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
+
+// check the bytes can be output as JSON string without escape
+func (e jsonEncoder) isSafeString(bs []byte) bool {
+	for _, c := range bs {
+		if c < ' ' || c == '"' || c == '\\' || c >= utf8.RuneSelf || e.escapeHTML && (c == '<' || c == '>' || c == '&') {
+			return false
+		}
+	}
+	return true
+}
+
+func (e jsonEncoder) appendMarshaler(buf []byte, m json.Marshaler) []byte {
+	bs, err := m.MarshalJSON()
+	if err != nil {
+		return e.appendString(buf, fmt.Sprint(m))
+	}
+
+	var out bytes.Buffer
+	if err = json.Compact(&out, bs); err != nil {
+		return e.appendString(buf, fmt.Sprint(m))
+	}
+
+	if e.escapeHTML {
+		w := bytes.NewBuffer(buf)
+		json.HTMLEscape(w, out.Bytes())
+		return w.Bytes()
+	}
+	return append(buf, out.Bytes()...)
+}
+
+// fallback to the encoding/json
+func (e jsonEncoder) appendReflect(buf []byte, v interface{}) []byte {
+	w := bytes.NewBuffer(buf)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(e.escapeHTML)
+	if err := enc.Encode(v); err != nil {
+		return e.appendString(buf, fmt.Sprint(v))
+	}
+
+	// remove the newline added by the Encode()
+	out := w.Bytes()
+	return out[:len(out)-1]
+}
+
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
 	"sync"
 	"time"
 )
@@ -26,8 +27,24 @@ type Logger struct {
 	LowerLevelName bool
 	MaxCallerDepth int
 
+	// ErrorPolicy on an handler returns error. default is ErrPolicyContinue
+	ErrorPolicy ErrorPolicy
+	// MaxRetries the max retry times for ErrPolicyRetry. default is DefaultMaxRetries
+	MaxRetries int
+	// RetryWait the wait time before the first retry of ErrPolicyRetry, it is doubled for each retry.
+	// default is DefaultRetryWait
+	RetryWait time.Duration
+	// ErrorHandler will be called on an handler returns error.
+	// default will print the error to os.Stderr
+	ErrorHandler ErrorHandler
+	// FallbackHandler if not nil, the handler errors will be sent to it
+	// as an ErrorLevel record of the ErrorsChannel
+	FallbackHandler Handler
+
 	// Reusable empty record
 	recordPool sync.Pool
+	// self-metrics of the logger
+	stats *stats
 
 	// handlers on exit
 	exitHandlers []func()
@@ -61,6 +78,10 @@ func NewWithName(name string) *Logger {
 		// options
 		ReportCaller:   true,
 		MaxCallerDepth: defaultMaxCallerDepth,
+		MaxRetries:     DefaultMaxRetries,
+		RetryWait:      DefaultRetryWait,
+		// self-metrics
+		stats: newStats(),
 	}
 
 	logger.recordPool.New = func() interface{} {
@@ -81,6 +102,7 @@ func (l *Logger) releaseRecord(r *Record) {
 	r.Data = nil
 	r.Extra = nil
 	r.Fields = nil
+	r.fieldKeys = r.fieldKeys[:0]
 	l.recordPool.Put(r)
 }
 
@@ -187,6 +209,7 @@ func (l *Logger) ResetProcessors() {
 // ResetHandlers for the logger
 func (l *Logger) ResetHandlers() {
 	l.handlers = make([]Handler, 0)
+	l.stats.resetHandlers()
 }
 
 // Exit logger handle
@@ -241,6 +264,7 @@ func (l *Logger) PushHandler(h Handler) {
 // SetHandlers for the logger
 func (l *Logger) SetHandlers(hs []Handler) {
 	l.handlers = hs
+	l.stats.resetHandlers()
 }
 
 // AddProcessor to the logger
@@ -448,15 +472,17 @@ func (l *Logger) Panicln(args ...interface{}) {
 //
 
 func (l *Logger) write(level Level, r *Record) {
-	var matchedHandlers []Handler
-	for _, handler := range l.handlers {
+	// the indexes of the matched handlers
+	var matched []int
+	for i, handler := range l.handlers {
 		if handler.IsHandling(level) {
-			matchedHandlers = append(matchedHandlers, handler)
+			matched = append(matched, i)
 		}
 	}
 
 	// log level is don't match
-	if len(matchedHandlers) == 0 {
+	if len(matched) == 0 {
+		l.stats.addDropped()
 		return
 	}
 
@@ -468,7 +494,7 @@ func (l *Logger) write(level Level, r *Record) {
 	}
 
 	// do write by handlers
-	l.doWrite(matchedHandlers, r)
+	l.doWrite(matched, r)
 
 	// If is Panic level
 	if level <= PanicLevel {
@@ -479,7 +505,7 @@ func (l *Logger) write(level Level, r *Record) {
 	}
 }
 
-func (l *Logger) doWrite(matchedHandlers []Handler, r *Record)  {
+func (l *Logger) doWrite(matched []int, r *Record) {
 	// init log time
 	r.initLogTime()
 
@@ -495,12 +521,75 @@ func (l *Logger) doWrite(matchedHandlers []Handler, r *Record)  {
 		l.processors[i].Process(r)
 	}
 
+	l.stats.addRecord(r)
+
 	// handling log record
-	for _, handler := range matchedHandlers {
-		if err := handler.Handle(r); err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "Failed to dispatch handler: %v\n", err)
+	for _, idx := range matched {
+		handler := l.handlers[idx]
+		start := time.Now()
+		err := handler.Handle(r)
+
+		// retry the failed handler
+		if err != nil && l.ErrorPolicy == ErrPolicyRetry {
+			wait := l.RetryWait
+			for i := 0; i < l.MaxRetries && err != nil; i++ {
+				if wait > 0 {
+					time.Sleep(wait)
+					wait *= 2
+				}
+				err = handler.Handle(r)
+			}
+		}
+
+		l.stats.observe(idx, time.Since(start), err)
+		if err == nil {
+			continue
+		}
+
+		l.handleError(handler, r, err)
+		if l.ErrorPolicy == ErrPolicyStop {
 			return
 		}
 	}
+}
+
+// check the handlers are same. the handlers with not comparable type are not same
+func isSameHandler(a, b Handler) bool {
+	if t := reflect.TypeOf(a); t != reflect.TypeOf(b) || !t.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+func (l *Logger) handleError(h Handler, r *Record, err error) {
+	if l.ErrorHandler != nil {
+		l.ErrorHandler(h, r, err)
+	} else {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to dispatch handler: %v\n", err)
+	}
+
+	// route to the fallback handler
+	if l.FallbackHandler == nil || isSameHandler(l.FallbackHandler, h) {
+		return
+	}
 
+	er := newRecord(l)
+	er.Time = time.Now()
+	er.Level = ErrorLevel
+	er.levelName = ErrorLevel.Name()
+	er.Channel = ErrorsChannel
+	er.Message = fmt.Sprintf("failed to dispatch handler %T: %v", h, err)
+	er.Data = M{
+		"handler": fmt.Sprintf("%T", h),
+		"error":   err.Error(),
+		// the original record
+		"channel": r.Channel,
+		"level":   r.LevelName(),
+		"message": r.Message,
+	}
+	er.initLogTime()
+
+	if ferr := l.FallbackHandler.Handle(er); ferr != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to dispatch fallback handler: %v\n", ferr)
+	}
 }
\ No newline at end of file
@@ -2,8 +2,11 @@ package slog_test
 
 import (
 	"bytes"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/gookit/goutil/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/tomorrowsky/slog"
 	"github.com/tomorrowsky/slog/handler"
@@ -49,3 +52,114 @@ func TestLogger_ReportCaller(t *testing.T) {
 	str := buf.String()
 	assert.Contains(t, str, `"caller":"logger_test.go`)
 }
+
+type failHandler struct {
+	handler.LevelsWithFormatter
+	handler.NopFlushClose
+	fails int
+	calls int
+}
+
+func (h *failHandler) Handle(*slog.Record) error {
+	h.calls++
+	if h.calls <= h.fails {
+		return errors.New("handle error")
+	}
+	return nil
+}
+
+func newFailHandler(fails int) *failHandler {
+	return &failHandler{
+		fails:               fails,
+		LevelsWithFormatter: handler.LevelsWithFormatter{Levels: slog.AllLevels},
+	}
+}
+
+func TestLogger_ErrorPolicy(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h1 := newFailHandler(10)
+	h2 := handler.NewIOWriterHandler(buf, slog.AllLevels)
+
+	var errs []string
+	l := slog.NewWithHandlers(h1, h2)
+	l.ErrorHandler = func(h slog.Handler, r *slog.Record, err error) {
+		errs = append(errs, r.Message+": "+err.Error())
+	}
+
+	// default: continue
+	assert.Equal(t, slog.ErrPolicyContinue, l.ErrorPolicy)
+	l.Info("message1")
+	assert.Contains(t, buf.String(), "message1")
+	assert.Equal(t, []string{"message1: handle error"}, errs)
+
+	// stop
+	buf.Reset()
+	l.ErrorPolicy = slog.ErrPolicyStop
+	l.Info("message2")
+	assert.Equal(t, "", buf.String())
+	assert.Len(t, errs, 2)
+
+	// retry
+	h1.calls, h1.fails = 0, 2
+	l.ErrorPolicy = slog.ErrPolicyRetry
+	l.RetryWait = 20 * time.Millisecond
+	start := time.Now()
+	l.Info("message3")
+	// wait 20ms + 40ms
+	assert.True(t, time.Since(start) >= 60*time.Millisecond)
+	assert.Contains(t, buf.String(), "message3")
+	assert.Equal(t, 3, h1.calls)
+	assert.Len(t, errs, 2)
+
+	assert.Equal(t, uint64(2), l.Stats().Errors)
+	assert.Equal(t, "retry", slog.ErrPolicyRetry.String())
+}
+
+func TestLogger_FallbackHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	fh := handler.NewIOWriterHandler(buf, slog.AllLevels)
+
+	l := slog.NewWithHandlers(newFailHandler(10))
+	l.FallbackHandler = fh
+
+	testutil.RewriteStderr()
+	l.Warn("warn message")
+	str := testutil.RestoreStderr()
+	assert.Contains(t, str, "Failed to dispatch handler: handle error")
+
+	str = buf.String()
+	assert.Contains(t, str, "["+slog.ErrorsChannel+"]")
+	assert.Contains(t, str, "[ERROR]")
+	assert.Contains(t, str, "failed to dispatch handler *slog_test.failHandler: handle error")
+	assert.Contains(t, str, "warn message")
+}
+
+// valueFailHandler is not comparable
+type valueFailHandler struct {
+	msgs *[]string
+	tags []string
+}
+
+func (h valueFailHandler) Close() error               { return nil }
+func (h valueFailHandler) Flush() error               { return nil }
+func (h valueFailHandler) IsHandling(slog.Level) bool { return true }
+
+func (h valueFailHandler) Handle(r *slog.Record) error {
+	*h.msgs = append(*h.msgs, r.Message)
+	return errors.New("handle error")
+}
+
+func TestLogger_FallbackHandler_notComparable(t *testing.T) {
+	var msgs, fallback []string
+	l := slog.NewWithHandlers(valueFailHandler{msgs: &msgs})
+	l.FallbackHandler = valueFailHandler{msgs: &fallback}
+
+	testutil.RewriteStderr()
+	l.Warn("warn message")
+	str := testutil.RestoreStderr()
+	assert.Contains(t, str, "Failed to dispatch fallback handler: handle error")
+
+	assert.Equal(t, []string{"warn message"}, msgs)
+	assert.Len(t, fallback, 1)
+	assert.Contains(t, fallback[0], "failed to dispatch handler slog_test.valueFailHandler")
+}
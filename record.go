@@ -33,6 +33,8 @@ type Record struct {
 	// Fields custom fields data.
 	// Contains all the fields set by the user.
 	Fields M
+	// the insertion order of the Fields keys. see FieldKeys()
+	fieldKeys []string
 
 	// Data log context data
 	Data M
@@ -116,8 +118,9 @@ func (r *Record) WithFields(fields M) *Record {
 		nr.Fields = make(M, len(fields))
 	}
 
-	for k, v := range fields {
-		nr.Fields[k] = v
+	for _, k := range sortedMapKeys(fields) {
+		nr.Fields[k] = fields[k]
+		nr.addFieldKey(k)
 	}
 	return nr
 }
@@ -149,9 +152,57 @@ func (r *Record) Copy() *Record {
 		Data:      dataCopy,
 		Extra:     extraCopy,
 		Fields:    fieldsCopy,
+		fieldKeys: copyKeys(r.fieldKeys),
 	}
 }
 
+// Snapshot create an detached copy of the record.
+//
+// The logger reuses the records by an pool, so an handler that keep the
+// record after Handle() returns(eg: async or batch handler), must use the snapshot.
+func (r *Record) Snapshot() *Record {
+	nr := &Record{
+		logger:      r.logger,
+		Time:        r.Time,
+		Level:       r.Level,
+		levelName:   r.levelName,
+		Channel:     r.Channel,
+		Message:     r.Message,
+		Ctx:         r.Ctx,
+		Data:        copyM(r.Data),
+		Extra:       copyM(r.Extra),
+		Fields:      copyM(r.Fields),
+		fieldKeys:   copyKeys(r.fieldKeys),
+		microSecond: r.microSecond,
+	}
+
+	if r.Caller != nil {
+		frame := *r.Caller
+		nr.Caller = &frame
+	}
+	return nr
+}
+
+// copy an map, will keep nil value
+func copyM(m M) M {
+	if m == nil {
+		return nil
+	}
+
+	nm := make(M, len(m))
+	for k, v := range m {
+		nm[k] = v
+	}
+	return nm
+}
+
+func copyKeys(keys []string) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+	return append([]string(nil), keys...)
+}
+
 //
 // ---------------------------------------------------------------------------
 // Direct set value to record
@@ -235,18 +286,21 @@ func (r *Record) AddField(name string, val interface{}) *Record {
 	}
 
 	r.Fields[name] = val
+	r.addFieldKey(name)
 	return r
 }
 
-// AddFields add new fields to the record
+// AddFields add new fields to the record. the keys in the fields are added by sorted order.
 func (r *Record) AddFields(fields M) *Record {
 	if r.Fields == nil {
 		r.Fields = fields
+		r.fieldKeys = r.fieldKeys[:0]
 		return r
 	}
 
-	for n, v := range fields {
-		r.Fields[n] = v
+	for _, n := range sortedMapKeys(fields) {
+		r.Fields[n] = fields[n]
+		r.addFieldKey(n)
 	}
 	return r
 }
@@ -254,9 +308,45 @@ func (r *Record) AddFields(fields M) *Record {
 // SetFields to the record
 func (r *Record) SetFields(fields M) *Record {
 	r.Fields = fields
+	r.fieldKeys = r.fieldKeys[:0]
 	return r
 }
 
+// FieldKeys get the keys of the Fields in the insertion order.
+// the keys are not added by the record methods(eg: set the Fields directly) are appended by sorted order.
+func (r *Record) FieldKeys() []string {
+	keys := make([]string, 0, len(r.Fields))
+	for _, k := range r.fieldKeys {
+		if _, ok := r.Fields[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+
+	if len(keys) == len(r.Fields) {
+		return keys
+	}
+
+	tracked := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		tracked[k] = true
+	}
+	for _, k := range sortedMapKeys(r.Fields) {
+		if !tracked[k] {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (r *Record) addFieldKey(name string) {
+	for _, k := range r.fieldKeys {
+		if k == name {
+			return
+		}
+	}
+	r.fieldKeys = append(r.fieldKeys, name)
+}
+
 // Object data on record TODO optimize performance
 // func (r *Record) Object(obj fmt.Stringer) *Record {
 // 	r.Data = ctx
@@ -408,13 +498,19 @@ func (r *Record) NewBuffer() *bytes.Buffer {
 	return r.Buffer
 }
 
-// LevelName get
+// LevelName get. if not set by logger, will return the name of the r.Level
 func (r *Record) LevelName() string {
+	if r.levelName == "" {
+		return r.Level.Name()
+	}
 	return r.levelName
 }
 
 // MicroSecond of the record
 func (r *Record) MicroSecond() int {
+	if r.microSecond == 0 {
+		return r.Time.Nanosecond() / 1000
+	}
 	return r.microSecond
 }
 
@@ -37,6 +37,8 @@ import (
 // Is a fast and usable Logger, which already contains the default formatting and handling capabilities
 type SugaredLogger struct {
 	*Logger
+	// HandlerMetrics report metrics to the logger stats
+	HandlerMetrics
 	// Formatter log message formatter. default use TextFormatter
 	Formatter Formatter
 	// Output output writer
@@ -100,7 +102,8 @@ func (sl *SugaredLogger) Handle(record *Record) error {
 		return err
 	}
 
-	_, err = sl.Output.Write(bts)
+	n, err := sl.Output.Write(bts)
+	sl.AddWritten(n)
 	return err
 }
 
@@ -0,0 +1,390 @@
+package slog
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets upper bounds(in seconds) of the handler latency histogram
+var DefaultLatencyBuckets = []float64{
+	0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1,
+}
+
+//
+// ---------------------------------------------------------------------------
+// Handler metrics
+// ---------------------------------------------------------------------------
+//
+
+// MetricsReporter interface.
+// Handlers that embed HandlerMetrics will report bytes written, dropped
+// records and rotations to the logger stats.
+type MetricsReporter interface {
+	// Metrics get the handler metrics
+	Metrics() *HandlerMetrics
+}
+
+// HandlerMetrics definition. can be embedded into a handler.
+//
+// Usage:
+// 	type MyHandler struct {
+// 		slog.HandlerMetrics
+// 		...
+// 	}
+//
+// 	// on handle record
+// 	n, err := h.out.Write(bts)
+// 	h.AddWritten(n)
+type HandlerMetrics struct {
+	mu sync.Mutex
+
+	written   uint64
+	dropped   uint64
+	rotations uint64
+}
+
+// Metrics get the handler metrics
+func (m *HandlerMetrics) Metrics() *HandlerMetrics {
+	return m
+}
+
+// AddWritten add written bytes number
+func (m *HandlerMetrics) AddWritten(n int) {
+	if n <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	m.written += uint64(n)
+	m.mu.Unlock()
+}
+
+// AddDropped add dropped(or sampled) records number
+func (m *HandlerMetrics) AddDropped(n int) {
+	if n <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	m.dropped += uint64(n)
+	m.mu.Unlock()
+}
+
+// AddRotation add an rotation of the log file
+func (m *HandlerMetrics) AddRotation() {
+	m.mu.Lock()
+	m.rotations++
+	m.mu.Unlock()
+}
+
+// Written bytes number
+func (m *HandlerMetrics) Written() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.written
+}
+
+// Dropped records number
+func (m *HandlerMetrics) Dropped() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dropped
+}
+
+// Rotations number
+func (m *HandlerMetrics) Rotations() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rotations
+}
+
+//
+// ---------------------------------------------------------------------------
+// Stats snapshot
+// ---------------------------------------------------------------------------
+//
+
+// Histogram snapshot of an latency histogram
+type Histogram struct {
+	// Bounds upper bounds of the buckets, in seconds
+	Bounds []float64 `json:"bounds"`
+	// Counts number of observations in each bucket.
+	// the last element is the bucket of +Inf
+	Counts []uint64 `json:"counts"`
+	// Count total observations number
+	Count uint64 `json:"count"`
+	// Sum of all observations, in seconds
+	Sum float64 `json:"sum"`
+}
+
+// HandlerStats snapshot for an handler
+type HandlerStats struct {
+	// Handled records number
+	Handled uint64 `json:"handled"`
+	// Errors number on call Handler.Handle()
+	Errors uint64 `json:"errors"`
+	// Bytes written by the handler. requires the handler to implement MetricsReporter
+	Bytes uint64 `json:"bytes"`
+	// Dropped records by the handler. requires the handler to implement MetricsReporter
+	Dropped uint64 `json:"dropped"`
+	// Rotations file rotations performed. requires the handler to implement MetricsReporter
+	Rotations uint64 `json:"rotations"`
+	// Latency histogram of the Handler.Handle()
+	Latency Histogram `json:"latency"`
+}
+
+// StatsSnapshot of the logger stats
+type StatsSnapshot struct {
+	// Logger name
+	Logger string `json:"logger"`
+	// Records number by level name
+	Records map[string]uint64 `json:"records"`
+	// Channels records number by channel name
+	Channels map[string]uint64 `json:"channels"`
+	// Dropped records number, there is no handler matched the level
+	Dropped uint64 `json:"dropped"`
+	// Errors total number of the handlers errors
+	Errors uint64 `json:"errors"`
+	// Handlers stats by handler name
+	Handlers map[string]HandlerStats `json:"handlers"`
+}
+
+// Total records number
+func (s StatsSnapshot) Total() (n uint64) {
+	for _, c := range s.Records {
+		n += c
+	}
+	return
+}
+
+//
+// ---------------------------------------------------------------------------
+// Stats collector
+// ---------------------------------------------------------------------------
+//
+
+// the stats of an handler. updated by atomic, so the handlers are not serialized by the stats.
+//
+// NOTICE: the uint64 fields are at the top for the 64-bit alignment of the atomic operations
+type handlerStats struct {
+	handled uint64
+	errors  uint64
+	// the latency histogram. the sum is in nanoseconds
+	count  uint64
+	sum    uint64
+	counts []uint64
+}
+
+func newHandlerStats(bounds []float64) *handlerStats {
+	return &handlerStats{counts: make([]uint64, len(bounds)+1)}
+}
+
+func (hs *handlerStats) observe(bounds []float64, d time.Duration, err error) {
+	sec := d.Seconds()
+	idx := len(bounds)
+	for i, bound := range bounds {
+		if sec <= bound {
+			idx = i
+			break
+		}
+	}
+
+	atomic.AddUint64(&hs.handled, 1)
+	atomic.AddUint64(&hs.counts[idx], 1)
+	atomic.AddUint64(&hs.count, 1)
+	if d > 0 {
+		atomic.AddUint64(&hs.sum, uint64(d))
+	}
+	if err != nil {
+		atomic.AddUint64(&hs.errors, 1)
+	}
+}
+
+// stats collector for the logger
+type stats struct {
+	// NOTICE: the atomic fields must be at the top for the 64-bit alignment
+	errors uint64
+
+	mu       sync.Mutex
+	buckets  []float64
+	records  map[Level]uint64
+	channels map[string]uint64
+	dropped  uint64
+
+	hmu sync.RWMutex
+	// the handlers stats by the index of the Logger.handlers.
+	// NOTICE: don't use the handler as map key, it may be not comparable.
+	handlers []*handlerStats
+}
+
+func newStats() *stats {
+	s := &stats{buckets: DefaultLatencyBuckets}
+	s.reset()
+	return s
+}
+
+func (s *stats) reset() {
+	s.mu.Lock()
+	s.dropped = 0
+	s.records = make(map[Level]uint64, len(AllLevels))
+	s.channels = make(map[string]uint64)
+	s.mu.Unlock()
+
+	atomic.StoreUint64(&s.errors, 0)
+	s.resetHandlers()
+}
+
+// reset the handlers stats. should call on the Logger.handlers is changed.
+func (s *stats) resetHandlers() {
+	s.hmu.Lock()
+	s.handlers = nil
+	s.hmu.Unlock()
+}
+
+func (s *stats) addRecord(r *Record) {
+	s.mu.Lock()
+	s.records[r.Level]++
+	s.channels[r.Channel]++
+	s.mu.Unlock()
+}
+
+func (s *stats) addDropped() {
+	s.mu.Lock()
+	s.dropped++
+	s.mu.Unlock()
+}
+
+// observe an Handle() call of the handler at the index
+func (s *stats) observe(idx int, d time.Duration, err error) {
+	s.hmu.RLock()
+	var hs *handlerStats
+	if idx < len(s.handlers) {
+		hs = s.handlers[idx]
+	}
+	s.hmu.RUnlock()
+
+	if hs == nil {
+		hs = s.handlerStats(idx)
+	}
+
+	hs.observe(s.buckets, d, err)
+	if err != nil {
+		atomic.AddUint64(&s.errors, 1)
+	}
+}
+
+// get or create the stats of the handler at the index
+func (s *stats) handlerStats(idx int) *handlerStats {
+	s.hmu.Lock()
+	defer s.hmu.Unlock()
+
+	if idx >= len(s.handlers) {
+		hss := make([]*handlerStats, idx+1)
+		copy(hss, s.handlers)
+		s.handlers = hss
+	}
+
+	if s.handlers[idx] == nil {
+		s.handlers[idx] = newHandlerStats(s.buckets)
+	}
+	return s.handlers[idx]
+}
+
+func (s *stats) snapshot(name string, handlers []Handler) StatsSnapshot {
+	s.mu.Lock()
+	ss := StatsSnapshot{
+		Logger:   name,
+		Records:  make(map[string]uint64, len(s.records)),
+		Channels: make(map[string]uint64, len(s.channels)),
+		Dropped:  s.dropped,
+		Errors:   atomic.LoadUint64(&s.errors),
+		Handlers: make(map[string]HandlerStats),
+	}
+
+	for lv, n := range s.records {
+		ss.Records[lv.Name()] = n
+	}
+	for ch, n := range s.channels {
+		ss.Channels[ch] = n
+	}
+	s.mu.Unlock()
+
+	s.hmu.RLock()
+	defer s.hmu.RUnlock()
+
+	// handler name counts, use for unique names
+	names := make(map[string]int)
+	for idx, hs := range s.handlers {
+		if hs == nil || idx >= len(handlers) {
+			continue
+		}
+
+		st := HandlerStats{
+			Handled: atomic.LoadUint64(&hs.handled),
+			Errors:  atomic.LoadUint64(&hs.errors),
+			Latency: Histogram{
+				Bounds: s.buckets,
+				Counts: make([]uint64, len(hs.counts)),
+				Count:  atomic.LoadUint64(&hs.count),
+				Sum:    time.Duration(atomic.LoadUint64(&hs.sum)).Seconds(),
+			},
+		}
+		for i := range hs.counts {
+			st.Latency.Counts[i] = atomic.LoadUint64(&hs.counts[i])
+		}
+
+		h := handlers[idx]
+		if mr, ok := h.(MetricsReporter); ok {
+			m := mr.Metrics()
+			st.Bytes = m.Written()
+			st.Dropped = m.Dropped()
+			st.Rotations = m.Rotations()
+		}
+
+		ss.Handlers[uniqueName(names, h)] = st
+	}
+	return ss
+}
+
+// build unique name for handler. eg: "*handler.FileHandler", "*handler.FileHandler#2"
+func uniqueName(names map[string]int, h Handler) string {
+	name := fmt.Sprintf("%T", h)
+
+	names[name]++
+	if n := names[name]; n > 1 {
+		name = fmt.Sprintf("%s#%d", name, n)
+	}
+	return name
+}
+
+//
+// ---------------------------------------------------------------------------
+// Logger stats API
+// ---------------------------------------------------------------------------
+//
+
+// Stats get an snapshot of the logger self-metrics
+func (l *Logger) Stats() StatsSnapshot {
+	return l.stats.snapshot(l.name, l.handlers)
+}
+
+// ResetStats reset all collected metrics of the logger
+func (l *Logger) ResetStats() {
+	l.stats.reset()
+}
+
+// PublishExpvar publish the logger stats to expvar by given name.
+//
+// NOTICE: like expvar.Publish, will panic if the name is already registered.
+//
+// Usage:
+// 	l.PublishExpvar("slog")
+// 	// then visit the "/debug/vars"
+func (l *Logger) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return l.Stats()
+	}))
+}
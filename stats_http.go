@@ -0,0 +1,161 @@
+package slog
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusHandler create an http.Handler, it will export the stats of
+// the loggers in the Prometheus text exposition format.
+//
+// Usage:
+// 	http.Handle("/metrics", slog.PrometheusHandler(l1, l2))
+func PrometheusHandler(loggers ...*Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		snapshots := make([]StatsSnapshot, 0, len(loggers))
+		for _, l := range loggers {
+			snapshots = append(snapshots, l.Stats())
+		}
+
+		_ = WritePrometheus(w, snapshots...)
+	})
+}
+
+// PrometheusHandler create an http.Handler for export the logger stats.
+// see slog.PrometheusHandler()
+func (l *Logger) PrometheusHandler() http.Handler {
+	return PrometheusHandler(l)
+}
+
+// WritePrometheus write the stats snapshots to writer in the Prometheus text format
+func WritePrometheus(w io.Writer, snapshots ...StatsSnapshot) error {
+	pw := &promWriter{w: bufio.NewWriter(w)}
+
+	pw.help("slog_records_total", "counter", "Total number of log records by level.")
+	for _, ss := range snapshots {
+		for _, name := range sortedKeys(ss.Records) {
+			pw.sample("slog_records_total", ss.Records[name], "logger", ss.Logger, "level", strings.ToLower(name))
+		}
+	}
+
+	pw.help("slog_channel_records_total", "counter", "Total number of log records by channel.")
+	for _, ss := range snapshots {
+		for _, name := range sortedKeys(ss.Channels) {
+			pw.sample("slog_channel_records_total", ss.Channels[name], "logger", ss.Logger, "channel", name)
+		}
+	}
+
+	pw.help("slog_dropped_records_total", "counter", "Total number of log records that no handler matched.")
+	for _, ss := range snapshots {
+		pw.sample("slog_dropped_records_total", ss.Dropped, "logger", ss.Logger)
+	}
+
+	handlerCounters := []struct {
+		name, help string
+		value      func(hs HandlerStats) uint64
+	}{
+		{"slog_handler_handled_total", "Total number of records passed to the handler.", func(hs HandlerStats) uint64 { return hs.Handled }},
+		{"slog_handler_errors_total", "Total number of errors returned by the handler.", func(hs HandlerStats) uint64 { return hs.Errors }},
+		{"slog_handler_written_bytes_total", "Total number of bytes written by the handler.", func(hs HandlerStats) uint64 { return hs.Bytes }},
+		{"slog_handler_dropped_total", "Total number of records dropped or sampled by the handler.", func(hs HandlerStats) uint64 { return hs.Dropped }},
+		{"slog_handler_rotations_total", "Total number of file rotations performed by the handler.", func(hs HandlerStats) uint64 { return hs.Rotations }},
+	}
+
+	for _, hc := range handlerCounters {
+		pw.help(hc.name, "counter", hc.help)
+		for _, ss := range snapshots {
+			for _, name := range sortedHandlerNames(ss.Handlers) {
+				pw.sample(hc.name, hc.value(ss.Handlers[name]), "logger", ss.Logger, "handler", name)
+			}
+		}
+	}
+
+	const latency = "slog_handler_handle_seconds"
+	pw.help(latency, "histogram", "Latency of the Handler.Handle() calls.")
+	for _, ss := range snapshots {
+		for _, name := range sortedHandlerNames(ss.Handlers) {
+			pw.histogram(latency, ss.Handlers[name].Latency, "logger", ss.Logger, "handler", name)
+		}
+	}
+
+	return pw.w.Flush()
+}
+
+type promWriter struct {
+	w *bufio.Writer
+}
+
+func (pw *promWriter) help(name, typ, help string) {
+	pw.w.WriteString("# HELP " + name + " " + help + "\n")
+	pw.w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func (pw *promWriter) sample(name string, val uint64, labels ...string) {
+	pw.line(name, strconv.FormatUint(val, 10), labels...)
+}
+
+func (pw *promWriter) histogram(name string, h Histogram, labels ...string) {
+	var cumulative uint64
+	for i, bound := range h.Bounds {
+		if i < len(h.Counts) {
+			cumulative += h.Counts[i]
+		}
+		pw.sample(name+"_bucket", cumulative, append(labels, "le", formatFloat(bound))...)
+	}
+
+	pw.sample(name+"_bucket", h.Count, append(labels, "le", "+Inf")...)
+	pw.line(name+"_sum", formatFloat(h.Sum), labels...)
+	pw.sample(name+"_count", h.Count, labels...)
+}
+
+func (pw *promWriter) line(name, val string, labels ...string) {
+	pw.w.WriteString(name)
+	if len(labels) > 0 {
+		pw.w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				pw.w.WriteByte(',')
+			}
+			pw.w.WriteString(labels[i] + `="` + escapeLabelValue(labels[i+1]) + `"`)
+		}
+		pw.w.WriteByte('}')
+	}
+
+	pw.w.WriteString(" " + val + "\n")
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys(mp map[string]uint64) []string {
+	keys := make([]string, 0, len(mp))
+	for k := range mp {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedHandlerNames(mp map[string]HandlerStats) []string {
+	keys := make([]string, 0, len(mp))
+	for k := range mp {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
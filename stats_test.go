@@ -0,0 +1,136 @@
+package slog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomorrowsky/slog"
+	"github.com/tomorrowsky/slog/handler"
+)
+
+type errorHandler struct {
+	handler.LevelsWithFormatter
+	handler.NopFlushClose
+}
+
+func (h *errorHandler) Handle(*slog.Record) error {
+	return errors.New("handle error")
+}
+
+func TestLogger_Stats(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := handler.NewIOWriterHandler(buf, slog.NormalLevels)
+
+	l := slog.NewWithHandlers(h)
+	l.ReportCaller = false
+	l.Info("info message")
+	l.Debug("debug message")
+	l.WithData(slog.M{"key": "val"}).Info("info message with data")
+	l.Error("error message") // not handled
+
+	ss := l.Stats()
+	assert.Equal(t, uint64(3), ss.Total())
+	assert.Equal(t, uint64(2), ss.Records["INFO"])
+	assert.Equal(t, uint64(1), ss.Records["DEBUG"])
+	assert.Equal(t, uint64(3), ss.Channels[slog.DefaultChannelName])
+	assert.Equal(t, uint64(1), ss.Dropped)
+	assert.Equal(t, uint64(0), ss.Errors)
+
+	hs, ok := ss.Handlers["*handler.IOWriterHandler"]
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), hs.Handled)
+	assert.Equal(t, uint64(buf.Len()), hs.Bytes)
+	assert.Equal(t, uint64(3), hs.Latency.Count)
+	assert.Len(t, hs.Latency.Counts, len(slog.DefaultLatencyBuckets)+1)
+
+	l.ResetStats()
+	assert.Equal(t, uint64(0), l.Stats().Total())
+	assert.Len(t, l.Stats().Handlers, 0)
+}
+
+func TestLogger_Stats_errors(t *testing.T) {
+	eh := &errorHandler{LevelsWithFormatter: handler.LevelsWithFormatter{Levels: slog.AllLevels}}
+	l := slog.NewWithHandlers(eh, &errorHandler{LevelsWithFormatter: handler.LevelsWithFormatter{Levels: slog.AllLevels}})
+	l.ReportCaller = false
+
+	l.Info("message")
+
+	ss := l.Stats()
+	assert.Equal(t, uint64(2), ss.Errors)
+	assert.Equal(t, uint64(1), ss.Handlers["*slog_test.errorHandler"].Errors)
+	assert.Equal(t, uint64(1), ss.Handlers["*slog_test.errorHandler#2"].Errors)
+}
+
+// valueHandler is not comparable, can't be used as map key
+type valueHandler struct {
+	lines *[]string
+	skip  []string
+}
+
+func (h valueHandler) Close() error               { return nil }
+func (h valueHandler) Flush() error               { return nil }
+func (h valueHandler) IsHandling(slog.Level) bool { return true }
+
+func (h valueHandler) Handle(r *slog.Record) error {
+	*h.lines = append(*h.lines, r.Message)
+	return nil
+}
+
+func TestLogger_Stats_valueHandler(t *testing.T) {
+	var lines []string
+	l := slog.NewWithHandlers(valueHandler{lines: &lines}, valueHandler{lines: &lines})
+	l.ReportCaller = false
+	l.Info("message")
+
+	ss := l.Stats()
+	assert.Equal(t, []string{"message", "message"}, lines)
+	assert.Equal(t, uint64(1), ss.Handlers["slog_test.valueHandler"].Handled)
+	assert.Equal(t, uint64(1), ss.Handlers["slog_test.valueHandler#2"].Handled)
+
+	// the handlers stats are reset on set handlers
+	l.SetHandlers([]slog.Handler{valueHandler{lines: &lines}})
+	assert.Len(t, l.Stats().Handlers, 0)
+	l.Info("message")
+	assert.Equal(t, uint64(1), l.Stats().Handlers["slog_test.valueHandler"].Handled)
+}
+
+func TestLogger_PublishExpvar(t *testing.T) {
+	l := slog.NewWithName("expvar-test")
+	l.AddHandler(handler.NewIOWriterHandler(new(bytes.Buffer), slog.AllLevels))
+	l.ReportCaller = false
+	l.PublishExpvar("slog-expvar-test")
+	l.Warn("warn message")
+
+	v := expvar.Get("slog-expvar-test")
+	assert.NotNil(t, v)
+
+	ss := slog.StatsSnapshot{}
+	assert.NoError(t, json.Unmarshal([]byte(v.String()), &ss))
+	assert.Equal(t, "expvar-test", ss.Logger)
+	assert.Equal(t, uint64(1), ss.Records["WARNING"])
+}
+
+func TestLogger_PrometheusHandler(t *testing.T) {
+	h := handler.NewIOWriterHandler(new(bytes.Buffer), slog.AllLevels)
+	l := slog.NewWithName("prom")
+	l.AddHandler(h)
+	l.ReportCaller = false
+	l.Info("info message")
+
+	w := httptest.NewRecorder()
+	l.PrometheusHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	str := w.Body.String()
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, str, "# TYPE slog_records_total counter")
+	assert.Contains(t, str, `slog_records_total{logger="prom",level="info"} 1`)
+	assert.Contains(t, str, `slog_channel_records_total{logger="prom",channel="application"} 1`)
+	assert.Contains(t, str, `slog_handler_handled_total{logger="prom",handler="*handler.IOWriterHandler"} 1`)
+	assert.Contains(t, str, `slog_handler_handle_seconds_bucket{logger="prom",handler="*handler.IOWriterHandler",le="+Inf"} 1`)
+	assert.Contains(t, str, `slog_handler_handle_seconds_count{logger="prom",handler="*handler.IOWriterHandler"} 1`)
+}
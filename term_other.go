@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package slog
+
+// get the columns of the terminal on stdout, not supported on current OS.
+func terminalColumns() int {
+	return 0
+}
@@ -0,0 +1,46 @@
+package slog
+
+import "context"
+
+type traceCtxKey struct{}
+
+// TraceContext the trace info of an record.
+type TraceContext struct {
+	// TraceID 16 bytes trace id, hex encoded
+	TraceID string
+	// SpanID 8 bytes span id, hex encoded
+	SpanID string
+	// Sampled flag
+	Sampled bool
+}
+
+// TraceExtractor extract the trace info from the context.
+//
+// Default will read it from the ContextWithTrace(). you can change it for
+// use the trace info of the tracing SDK. eg: OpenTelemetry
+//
+// Usage:
+// 	slog.TraceExtractor = func(ctx context.Context) (slog.TraceContext, bool) {
+// 		sc := trace.SpanContextFromContext(ctx)
+// 		return slog.TraceContext{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String()}, sc.IsValid()
+// 	}
+var TraceExtractor = TraceFromContext
+
+// ContextWithTrace create new context with the trace info
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceCtxKey{}, tc)
+}
+
+// TraceFromContext get the trace info from the context
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceCtxKey{}).(TraceContext)
+	return tc, ok
+}
+
+// TraceContext get the trace info from the Record.Ctx, by the TraceExtractor.
+func (r *Record) TraceContext() (TraceContext, bool) {
+	if r.Ctx == nil || TraceExtractor == nil {
+		return TraceContext{}, false
+	}
+	return TraceExtractor(r.Ctx)
+}
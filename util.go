@@ -159,29 +159,38 @@ func formatArgsWithSpaces(vs []interface{}) (buf []byte) {
 	return
 }
 
-// EncodeToString data to string
+// EncodeToString data to string. the map will be encoded as an JSON object, the keys are sorted.
 func EncodeToString(v interface{}) string {
-	if _, ok := v.(map[string]interface{}); ok {
-		return mapToString(v.(map[string]interface{}))
+	switch mp := v.(type) {
+	case M:
+		return mapToString(mp)
+	case map[string]interface{}:
+		return mapToString(mp)
 	}
 
 	str, _ := strutil.AnyToString(v, false)
 	return str
 }
 
-func mapToString(mp map[string]interface{}) string {
-	var buf []byte
-	buf = append(buf, '{')
-
-	for k, val := range mp {
-		buf = append(buf, k...)
-		buf = append(buf, ':')
-
-		str, _ := strutil.AnyToString(val, false)
-		buf = append(buf, str...)
-		buf = append(buf, ',')
+// EncodeToKVString data to string. the map will be encoded as the sorted "k=v" pairs,
+// the nested maps are flattened to dotted keys. eg: "ip=127.0.0.1 user.id=23"
+//
+// Usage:
+// 	f := slog.NewTextFormatter()
+// 	f.EncodeFunc = slog.EncodeToKVString
+func EncodeToKVString(v interface{}) string {
+	f := &LogfmtFormatter{TimeFormat: DefaultTimeFormat}
+	switch mp := v.(type) {
+	case M:
+		return strutil.Byte2str(f.appendMap(nil, "", mp))
+	case map[string]interface{}:
+		return strutil.Byte2str(f.appendMap(nil, "", mp))
 	}
-	buf = append(buf, '}')
 
-	return strutil.Byte2str(buf)
+	return EncodeToString(v)
+}
+
+// encode the map to JSON object, the keys are sorted.
+func mapToString(mp map[string]interface{}) string {
+	return strutil.Byte2str(jsonEncoder{}.appendMap(make([]byte, 0, 64), mp))
 }